@@ -0,0 +1,86 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/mixcode/codepage-unzip/codepagezip"
+)
+
+// how to extract entries of NTFS alternate data streams, named like file.txt:stream
+const (
+	ADSAuto    = "auto"    // streams on Windows, sidecar files elsewhere; only for names whose file is also in the archive
+	ADSStream  = "stream"  // an alternate data stream of the file; needs an NTFS output directory on Windows
+	ADSSidecar = "sidecar" // a separate file named file.txt_stream
+	ADSSkip    = "skip"    // do not extract streams
+)
+
+var adsPolicy = ADSAuto
+
+// entries extracted as streams or sidecars; they are written after the other entries
+var streamEntries = make(map[*zip.File]bool)
+
+func checkADSPolicy() error {
+	switch adsPolicy {
+	case ADSAuto, ADSStream, ADSSidecar, ADSSkip:
+		return nil
+	}
+	return fmt.Errorf("unknown -ads policy %q", adsPolicy)
+}
+
+// split an entry name in the file:stream notation of NTFS alternate data streams.
+// A trailing :$DATA stream type is dropped; file::$DATA names the file itself.
+func splitStream(name string) (file, stream string, ok bool) {
+	slash := strings.LastIndexAny(name, `/\`)
+	base := name[slash+1:]
+	colon := strings.IndexByte(base, ':')
+	if colon <= 0 {
+		return name, "", false
+	}
+	if slash < 0 && colon == 1 {
+		return name, "", false // a drive letter
+	}
+	file = name[:slash+1+colon]
+	stream = strings.TrimSuffix(base[colon+1:], ":$DATA")
+	if stream == "" {
+		return file, "", false
+	}
+	return file, stream, true
+}
+
+// the names of the entries of an archive, without trailing slashes
+func entryNameSet(files []*zip.File) map[string]bool {
+	set := make(map[string]bool, len(files))
+	for _, f := range files {
+		set[strings.TrimSuffix(f.Name, "/")] = true
+	}
+	return set
+}
+
+// whether an entry named like file:stream is an alternate data stream.
+// With auto, only a name whose file is also an entry in the archive is, so that names like "backup 12:30.log" are kept.
+// The raw name is split; a colon byte is never part of a multibyte character in the supported codepages.
+func isStreamEntry(raw string, names map[string]bool) bool {
+	file, _, ok := splitStream(raw)
+	if !ok {
+		return false
+	}
+	return adsPolicy != ADSAuto || names[file]
+}
+
+// the output name of an alternate data stream of file, by -ads
+func streamName(file, stream string) string {
+	policy := adsPolicy
+	if policy == ADSAuto {
+		policy = ADSSidecar
+		if runtime.GOOS == "windows" {
+			policy = ADSStream
+		}
+	}
+	if policy == ADSStream {
+		return file + ":" + windowsSafeComponent(stream)
+	}
+	return file + "_" + codepagezip.SanitizeComponent(stream)
+}
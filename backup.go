@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// -backup-existing[=dir]
+type backupOption struct {
+	enabled bool
+	dir     string // empty for the default directory
+	current string // the directory of the current archive, chosen at its first backup
+}
+
+var backupExisting backupOption
+
+func (b *backupOption) String() string {
+	if b == nil || !b.enabled {
+		return ""
+	}
+	return b.dir
+}
+
+func (b *backupOption) Set(s string) error {
+	switch s {
+	case "true":
+		b.enabled, b.dir = true, ""
+	case "false":
+		b.enabled, b.dir = false, ""
+	default:
+		b.enabled, b.dir = true, s
+	}
+	return nil
+}
+
+// the option may be given without a value
+func (b *backupOption) IsBoolFlag() bool { return true }
+
+// move an existing file about to be overwritten into the backup directory, keeping its path relative to the output directory root
+func backupFile(root, outpath string) (err error) {
+	if backupExisting.current == "" {
+		backupExisting.current = backupExisting.dir
+		if backupExisting.current == "" {
+			backupExisting.current = filepath.Join(root, ".codepage-unzip-backup", time.Now().Format("20060102-150405"))
+		}
+	}
+	rel, err := filepath.Rel(root, outpath)
+	if err != nil {
+		return
+	}
+	dst := filepath.Join(backupExisting.current, rel)
+	err = makeDir(filepath.Dir(dst))
+	if err != nil {
+		return
+	}
+	// do not clobber an earlier backup
+	for i := 1; ; i++ {
+		if _, e := os.Lstat(dst); os.IsNotExist(e) {
+			break
+		}
+		dst = filepath.Join(backupExisting.current, rel) + "." + strconv.Itoa(i)
+	}
+	err = renameFile(outpath, dst)
+	if err != nil {
+		// the backup directory may be on another filesystem
+		err = copyFile(dst, outpath)
+		if err != nil {
+			return
+		}
+		err = removeFile(outpath)
+		if err != nil {
+			return
+		}
+	}
+	if !quiet {
+		fmt.Printf("backed up %s to %s\n", outpath, dst)
+	}
+	return
+}
+
+func copyFile(dst, src string) (err error) {
+	fi, err := os.Open(src)
+	if err != nil {
+		return
+	}
+	defer fi.Close()
+	st, err := fi.Stat()
+	if err != nil {
+		return
+	}
+	fo, err := createNewFile(dst, st.Mode().Perm())
+	if err != nil {
+		return
+	}
+	_, err = io.Copy(fo, fi)
+	if e := fo.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		removeFile(dst)
+		return
+	}
+	return setFileTimes(dst, st.ModTime(), st.ModTime())
+}
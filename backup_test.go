@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// each archive of a batch backs up into its own directory, under its own output directory
+func TestBackupPerArchive(t *testing.T) {
+	defer func(b backupOption, q bool) { backupExisting, quiet = b, q }(backupExisting, quiet)
+	backupExisting, quiet = backupOption{enabled: true}, true
+
+	var dirs []string
+	for _, name := range []string{"one", "two"} {
+		resetArchiveState()
+		root := filepath.Join(t.TempDir(), name)
+		if err := os.Mkdir(root, 0777); err != nil {
+			t.Fatal(err)
+		}
+		outpath := filepath.Join(root, "f.txt")
+		if err := os.WriteFile(outpath, []byte(name), 0666); err != nil {
+			t.Fatal(err)
+		}
+		if err := backupFile(root, outpath); err != nil {
+			t.Fatal(err)
+		}
+		if !isBeneath(root, backupExisting.current) {
+			t.Errorf("%s: backed up into %s", name, backupExisting.current)
+		}
+		if b, err := os.ReadFile(filepath.Join(backupExisting.current, "f.txt")); err != nil || string(b) != name {
+			t.Errorf("%s: backup is %q, %v", name, b, err)
+		}
+		dirs = append(dirs, backupExisting.current)
+	}
+	if dirs[0] == dirs[1] {
+		t.Errorf("both archives backed up into %s", dirs[0])
+	}
+	if backupExisting.dir != "" {
+		t.Errorf("the default directory was kept as -backup-existing=%s", backupExisting.dir)
+	}
+}
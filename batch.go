@@ -0,0 +1,224 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mixcode/codepage-unzip/codepagezip"
+)
+
+var (
+	archivesFrom0  = "" // read NUL-separated archive names from this file; "-" for stdin
+	destPerArchive = "" // template of the output directory of each archive, under -d
+	manifestFile   = "" // also process the archives of this census report, each with its codepage
+)
+
+// read a NUL-separated list of names, as written by find -print0
+func readArchiveList(src string) (list []string, err error) {
+	if src == "" {
+		return
+	}
+	var r io.Reader = os.Stdin
+	if src != "-" {
+		f, err := os.Open(src)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return
+	}
+	for _, name := range bytes.Split(b, []byte{0}) {
+		if len(name) > 0 {
+			list = append(list, string(name))
+		}
+	}
+	return
+}
+
+// read the archives of a census report in CSV, as written by census and maybe edited, and their codepages.
+// Only the archive and codepage columns are read; an empty codepage leaves the archive to -f.
+func readManifest(filename string) (list []string, codepages map[string]string, err error) {
+	if filename == "" {
+		return
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	if len(rows) == 0 {
+		return
+	}
+	archive, codepage := slices.Index(rows[0], "archive"), slices.Index(rows[0], "codepage")
+	if archive < 0 {
+		return nil, nil, fmt.Errorf("%s: no archive column", filename)
+	}
+	codepages = make(map[string]string)
+	for _, row := range rows[1:] {
+		if archive >= len(row) || row[archive] == "" {
+			continue
+		}
+		list = append(list, row[archive])
+		if codepage >= 0 && codepage < len(row) {
+			codepages[row[archive]] = row[codepage]
+		}
+	}
+	return
+}
+
+// expand a -dest-per-archive template for an archive:
+// {dir} is the directory of the archive, {base} its name without the extension, {name} its name, and {ext} its extension without the dot.
+// The result is put under dest, and cannot escape it.
+func expandDestTemplate(tmpl, dest, zipname string) string {
+	dir, name := filepath.Split(zipname)
+	ext := filepath.Ext(name)
+	r := strings.NewReplacer(
+		"{dir}", filepath.ToSlash(dir),
+		"{base}", strings.TrimSuffix(name, ext),
+		"{name}", name,
+		"{ext}", strings.TrimPrefix(ext, "."),
+	)
+	return filepath.Join(dest, filepath.FromSlash(codepagezip.SanitizePath(r.Replace(tmpl))))
+}
+
+// clear the state run() keeps about an archive
+func resetArchiveState() {
+	hasPath = make(map[string]bool)
+	nameMap = nil
+	box = nil
+	ckpt = nil
+	pendingLinks = nil
+	pendingForks = nil
+	unconverted = make(map[*zip.File]bool)
+	streamEntries = make(map[*zip.File]bool)
+	dirtyDirs = make(map[string]bool)
+	extractedFiles = nil
+	warnings = make(map[string]int)
+	upToDate = 0
+	backupExisting.current = ""
+}
+
+// process each of the archives given as arguments, in the -archives-from-0 list and in the -manifest,
+// the last with the codepages it gives instead of -f.
+// With -dest-per-archive, each archive is extracted into its own directory, otherwise into -d.
+// With -jobs, archives are processed at once, each in a process of its own.
+// A failed archive is reported and the rest are processed.
+func runBatch(args []string) error {
+	list, err := readArchiveList(archivesFrom0)
+	if err != nil {
+		return err
+	}
+	list = append(args, list...)
+	manifest, codepages, err := readManifest(manifestFile)
+	if err != nil {
+		return err
+	}
+	list = append(list, manifest...)
+	if len(list) == 0 {
+		return fmt.Errorf("no archives are given")
+	}
+
+	// options run() may change for an archive
+	dest, names, from := destDir, writeMap, convertFrom
+
+	var pool *jobPool
+	if batchJobs > 1 {
+		pool, err = newJobPool(len(list))
+		if err != nil {
+			return err
+		}
+	}
+	failed := 0
+	for _, zipname := range list {
+		resetArchiveState()
+		destDir, writeMap, convertFrom = dest, names, from
+		if cp := codepages[zipname]; cp != "" {
+			convertFrom = cp
+		}
+		if destPerArchive != "" {
+			destDir = expandDestTemplate(destPerArchive, dest, zipname)
+			err = os.MkdirAll(destDir, fs.ModePerm)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s: %v\n", zipname, err)
+				failed++
+				continue
+			}
+		}
+		if pool != nil {
+			pool.start(zipname)
+			continue
+		}
+		if !quiet {
+			fmt.Printf("Archive: %s\n", zipname)
+		}
+		if useMarkers && cmd == CmdUnzip {
+			err = runMarked(zipname)
+		} else {
+			err = run([]string{zipname})
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", zipname, err)
+			failed++
+		}
+	}
+	if pool != nil {
+		failed += pool.wait()
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d archives failed", failed, len(list))
+	}
+	return nil
+}
+
+// extract an archive unless the marker file of the destination records it with the same contents and options,
+// and record it when extracted
+func runMarked(zipname string) error {
+	if isURL(zipname) {
+		return fmt.Errorf("-marker is not supported for archives given as URLs")
+	}
+	dest := destDir
+	abs, err := filepath.Abs(zipname)
+	if err != nil {
+		return err
+	}
+	sum, err := fileSHA256(zipname)
+	if err != nil {
+		return err
+	}
+	opts := outputOptions()
+	m, err := loadMarkers(dest)
+	if err != nil {
+		return err
+	}
+	if rec, ok := m[abs]; ok && rec.SHA256 == sum && rec.Options == opts {
+		if !quiet {
+			fmt.Printf("already extracted; skipping\n")
+		}
+		return nil
+	}
+	err = run([]string{zipname})
+	if err != nil {
+		return err
+	}
+	m[abs] = markerRecord{SHA256: sum, Options: opts, Time: time.Now()}
+	return m.save(dest)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var censusFormat = "csv" // the format of the census report: csv or json
+
+// what the census reports about an archive
+type censusRow struct {
+	Archive    string   `json:"archive"`
+	Entries    int      `json:"entries"`
+	Methods    []string `json:"methods"`
+	Legacy     int      `json:"legacy_names"` // names that are neither ASCII nor UTF-8
+	Codepage   string   `json:"codepage"`     // the likely codepage of those names
+	Confidence float64  `json:"confidence"`
+	NeedsFix   bool     `json:"needs_fix"`
+	Error      string   `json:"error,omitempty"`
+}
+
+var censusHeader = []string{"archive", "entries", "methods", "legacy_names", "codepage", "confidence", "needs_fix", "error"}
+
+func (c *censusRow) csv() []string {
+	return []string{
+		c.Archive,
+		strconv.Itoa(c.Entries),
+		strings.Join(c.Methods, " "),
+		strconv.Itoa(c.Legacy),
+		c.Codepage,
+		strconv.FormatFloat(c.Confidence, 'f', 2, 64),
+		strconv.FormatBool(c.NeedsFix),
+		c.Error,
+	}
+}
+
+// take the census of an archive; an archive that cannot be read is reported in the row
+func censusArchive(zipname string) censusRow {
+	row := censusRow{Archive: zipname, Methods: []string{}}
+	zr, err := zip.OpenReader(zipname)
+	if err != nil {
+		row.Error = err.Error()
+		return row
+	}
+	defer zr.Close()
+	row.Entries = len(zr.File)
+	methods := make(map[string]bool)
+	for _, f := range zr.File {
+		methods[methodName(f.Method)] = true
+	}
+	for m := range methods {
+		row.Methods = append(row.Methods, m)
+	}
+	sort.Strings(row.Methods)
+
+	d := detectEncoding(zr.File)
+	row.Legacy = d.samples
+	row.NeedsFix = d.samples > 0
+	if row.NeedsFix {
+		row.Codepage, row.Confidence = d.encoding, d.confidence
+	}
+	return row
+}
+
+// find the ZIP archives under the directories, in lexical order; files given are taken as they are.
+// Directories that cannot be read under them are reported and passed over.
+func findArchives(roots []string) ([]string, error) {
+	var list []string
+	for _, root := range roots {
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if p == root {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				return nil
+			}
+			if p == root && !d.IsDir() || d.Type().IsRegular() && strings.EqualFold(filepath.Ext(p), ".zip") {
+				list = append(list, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// report the likely filename codepage, the entry counts and the compression methods of each ZIP archive
+// under the directories, for planning the conversion of a collection
+func runCensus(args []string) (err error) {
+	if censusFormat != "csv" && censusFormat != "json" {
+		return fmt.Errorf("unknown -census-format '%s'", censusFormat)
+	}
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+	list, err := findArchives(args)
+	if err != nil {
+		return
+	}
+	rows := make([]censusRow, 0, len(list))
+	codepages := make(map[string]int)
+	fix := 0
+	for _, zipname := range list {
+		row := censusArchive(zipname)
+		if row.NeedsFix {
+			codepages[row.Codepage]++
+			fix++
+		}
+		rows = append(rows, row)
+	}
+
+	if censusFormat == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(rows)
+	} else {
+		w := csv.NewWriter(os.Stdout)
+		w.Write(censusHeader)
+		for i := range rows {
+			w.Write(rows[i].csv())
+		}
+		w.Flush()
+		err = w.Error()
+	}
+	if err != nil {
+		return
+	}
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "%d archives, %d with names in a legacy codepage\n", len(rows), fix)
+		if len(codepages) > 1 {
+			var counts []string
+			for cp, n := range codepages {
+				counts = append(counts, fmt.Sprintf("%s (%d)", cp, n))
+			}
+			sort.Strings(counts)
+			fmt.Fprintf(os.Stderr, "They need different codepages: %s; extract them with -manifest and this report in CSV\n", strings.Join(counts, ", "))
+		}
+	}
+	return
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// write a ZIP file of entries with raw names and contents, flagged as UTF-8 if the names are valid UTF-8
+func writeZip(t *testing.T, filename string, files [][2]string) {
+	t.Helper()
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for _, nc := range files {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: nc[0], Method: zip.Deflate})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(nc[1]))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCensusArchive(t *testing.T) {
+	dir := t.TempDir()
+	sjis := filepath.Join(dir, "sjis.zip")
+	writeZip(t, sjis, [][2]string{{"\x83e\x83X\x83g/\x93\xfa\x96{\x8c\xea.txt", "a"}, {"readme.txt", "b"}})
+	utf8 := filepath.Join(dir, "utf8.ZIP")
+	writeZip(t, utf8, [][2]string{{"日本語.txt", "a"}})
+	broken := filepath.Join(dir, "sub", "broken.zip")
+	os.Mkdir(filepath.Dir(broken), 0777)
+	os.WriteFile(broken, []byte("PK"), 0666)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0666)
+
+	list, err := findArchives([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{sjis, broken, utf8}; !reflect.DeepEqual(list, want) {
+		t.Fatalf("findArchives() = %q, want %q", list, want)
+	}
+
+	row := censusArchive(sjis)
+	if row.Entries != 2 || row.Legacy != 1 || row.Codepage != "CP932" || !row.NeedsFix || row.Error != "" {
+		t.Errorf("%s: %+v", sjis, row)
+	}
+	if !reflect.DeepEqual(row.Methods, []string{"deflate"}) {
+		t.Errorf("%s: methods %q", sjis, row.Methods)
+	}
+	if row := censusArchive(utf8); row.NeedsFix || row.Codepage != "" {
+		t.Errorf("%s: %+v", utf8, row)
+	}
+	if row := censusArchive(broken); row.Error == "" {
+		t.Errorf("%s: no error", broken)
+	}
+}
+
+func TestReadManifest(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "census.csv")
+	os.WriteFile(filename, []byte("codepage,archive,note\nCP932,a.zip\n,b.zip,utf-8\nEUC-KR,\"c,d.zip\",x\n,,\n"), 0666)
+	list, codepages, err := readManifest(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a.zip", "b.zip", "c,d.zip"}; !reflect.DeepEqual(list, want) {
+		t.Errorf("archives %q, want %q", list, want)
+	}
+	if want := map[string]string{"a.zip": "CP932", "b.zip": "", "c,d.zip": "EUC-KR"}; !reflect.DeepEqual(codepages, want) {
+		t.Errorf("codepages %q, want %q", codepages, want)
+	}
+
+	os.WriteFile(filename, []byte("name,codepage\na.zip,CP932\n"), 0666)
+	if _, _, err := readManifest(filename); err == nil {
+		t.Error("a report without an archive column was accepted")
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const checkpointInterval = 2 * time.Second // how often the checkpoint file is saved
+
+// a completed entry in a checkpoint
+type checkpointEntry struct {
+	CRC32   uint32    `json:"crc32"`
+	Size    uint64    `json:"size"`
+	Path    string    `json:"path"`    // the output path
+	ModTime time.Time `json:"modTime"` // modification time of the output file when it was completed
+}
+
+// checkpoint records the entries completed so far, so that an interrupted extraction can be resumed
+type checkpoint struct {
+	Archive     string                     `json:"archive"`
+	ArchiveSize int64                      `json:"archiveSize"`
+	Entries     map[string]checkpointEntry `json:"entries"`           // by the raw name in hex
+	Staging     string                     `json:"staging,omitempty"` // the staging directory of -staging, until it is moved into place
+
+	filename string
+	saved    time.Time
+}
+
+// load a checkpoint file, or start a new one if it does not exist
+func loadCheckpoint(filename, archive string) (c *checkpoint, err error) {
+	st, err := os.Stat(archive)
+	if err != nil {
+		return
+	}
+	c = &checkpoint{filename: filename, saved: time.Now()}
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		c.Archive, c.ArchiveSize = filepath.Base(archive), st.Size()
+		c.Entries = make(map[string]checkpointEntry)
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, c)
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint %s: %w", filename, err)
+	}
+	if c.Archive != filepath.Base(archive) || c.ArchiveSize != st.Size() {
+		return nil, fmt.Errorf("checkpoint %s is for another archive %s", filename, c.Archive)
+	}
+	if c.Entries == nil {
+		c.Entries = make(map[string]checkpointEntry)
+	}
+	return
+}
+
+// check if an entry has been completed into dir and its output is still intact.
+// An output elsewhere, like in the staging directory of an earlier run, does not count.
+func (c *checkpoint) done(entry *zip.File, dir string) bool {
+	e, ok := c.Entries[hex.EncodeToString([]byte(entry.Name))]
+	if !ok || e.CRC32 != entry.CRC32 || e.Size != entry.UncompressedSize64 {
+		return false
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil || !isBeneath(absDir, e.Path) {
+		return false
+	}
+	st, err := os.Stat(e.Path)
+	if err != nil {
+		return false
+	}
+	if st.IsDir() {
+		return true
+	}
+	return uint64(st.Size()) == e.Size && st.ModTime().Equal(e.ModTime)
+}
+
+// record a completed entry
+func (c *checkpoint) record(entry *zip.File, path string) error {
+	st, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	c.Entries[hex.EncodeToString([]byte(entry.Name))] = checkpointEntry{
+		CRC32:   entry.CRC32,
+		Size:    entry.UncompressedSize64,
+		Path:    path,
+		ModTime: st.ModTime(),
+	}
+	if time.Since(c.saved) < checkpointInterval {
+		return nil
+	}
+	return c.save()
+}
+
+// record that the staging directory has been moved into place as final
+func (c *checkpoint) moved(staging, final string) {
+	absStaging, err1 := filepath.Abs(staging)
+	absFinal, err2 := filepath.Abs(final)
+	if err1 != nil || err2 != nil {
+		return
+	}
+	for k, e := range c.Entries {
+		if rel, err := filepath.Rel(absStaging, e.Path); err == nil && isBeneath(absStaging, e.Path) {
+			e.Path = filepath.Join(absFinal, rel)
+			c.Entries[k] = e
+		}
+	}
+	c.Staging = ""
+}
+
+// write the checkpoint file
+func (c *checkpoint) save() error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	// replace the file atomically so that a crash does not leave a broken checkpoint
+	tmp := c.filename + ".tmp"
+	err = os.WriteFile(tmp, data, 0666)
+	if err != nil {
+		return err
+	}
+	c.saved = time.Now()
+	return os.Rename(tmp, c.filename)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckpointStaging(t *testing.T) {
+	tmp := t.TempDir()
+	staging, final, other := filepath.Join(tmp, "staging"), filepath.Join(tmp, "final"), filepath.Join(tmp, "other")
+	for _, dir := range []string{staging, other} {
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entry := &zip.File{FileHeader: zip.FileHeader{Name: "a.txt", CRC32: 1, UncompressedSize64: 4}}
+	if err := os.WriteFile(filepath.Join(staging, "a.txt"), []byte("data"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &checkpoint{filename: filepath.Join(tmp, "ck.json"), Entries: map[string]checkpointEntry{}}
+	if err := c.record(entry, filepath.Join(staging, "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if !c.done(entry, staging) {
+		t.Error("the entry is not done in the staging directory it was extracted into")
+	}
+	if c.done(entry, other) {
+		t.Error("the entry is done in a directory it was not extracted into")
+	}
+
+	if err := os.Rename(staging, final); err != nil {
+		t.Fatal(err)
+	}
+	c.Staging = staging
+	c.moved(staging, final)
+	if !c.done(entry, final) || c.Staging != "" {
+		t.Errorf("after moving, done = %v and staging = %q", c.done(entry, final), c.Staging)
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// A form of the command line, for the usage message and the man page.
+type commandSpec struct {
+	name     string // subcommand; empty for extracting and listing
+	synopsis string // arguments after the program name
+	summary  string // what the subcommand does, for the man page
+}
+
+var commandSpecs = []commandSpec{
+	{"", "[flags] [-f codepage] ZIPfile", "Extract the files, or list them with -l."},
+	{"", "[flags] [-f codepage] [-dest-per-archive template] [-archives-from-0 LIST] [-manifest census.csv] [-jobs N] ZIPfile...", "Extract several archives, into -d or each into its own directory."},
+	{"add", "add ZIPfile files... [flags]", "Add files to the archive under UTF-8 names; -f is the codepage of the existing names, for replacing entries, and -encrypt encrypts the new ones with AES-256."},
+	{"delete", "delete ZIPfile patterns... [-f codepage]", "Delete the entries whose converted names match the patterns."},
+	{"rename", "rename ZIPfile pattern newname [-f codepage]", "Rename the entries whose converted names match the pattern."},
+	{"translit", "translit [ZIPfile] [-translit schemes] [-f codepage]", "Print the ASCII names -ascii-slugs would give the entries, or transliterate the lines of stdin."},
+	{"comment", "comment ZIPfile [entry] [-set-comment text | -transcode-comments] [-f codepage]", "Print, set or convert the comment of the archive or of an entry."},
+	{"census", "census [directories...] [-census-format csv|json]", "Report the likely filename codepage, the number of entries and the compression methods of each ZIP archive under the directories, and whether its names need fixing."},
+	{"version", "version", "Print the version, commit, build tags, converter and supported formats."},
+	{"help", "help [--man]", "Print this usage message, or a man page in roff format."},
+}
+
+// Paragraphs describing the program, for the usage message and the man page.
+var descriptionSpec = []string{
+	"Filenames are converted from the specified codepage to unicode.\n",
+	"Run with -list-encodings for the available codepages.\n",
+	"A gzip, bzip2 or xz compressed file may be given instead of a ZIP; the original filename stored in gzip is converted in the same way.\n",
+	"An ISO9660 image may be given as well; Joliet names are read as they are, and Rock Ridge or plain ISO9660 names are converted.\n",
+	"A ZIP archive may also be given as an http or https URL, from a server supporting range requests; only the parts needed are downloaded.\n",
+}
+
+// A command line flag: its name, the variable it sets, and its description.
+// The type of the variable decides the type of the flag.
+type flagSpec struct {
+	name  string
+	value any
+	usage string
+}
+
+// The flags of all commands. list is set by -l, which only selects the command.
+func flagSpecs(list *bool) []flagSpec {
+	return []flagSpec{
+		{"l", list, "print filenames without extracting"},
+		{"d", &destDir, "Directory to which to extract files"},
+		{"dest-per-archive", &destPerArchive, "extract each archive into a directory under -d made from this template, e.g. '{dir}/{base}'; {dir}, {base}, {name} and {ext} are replaced by the directory, the name without the extension, the name, and the extension of the archive"},
+		{"marker", &useMarkers, "record each extracted archive in a " + markerFilename + " file in its destination, and skip archives recorded with the same contents and options"},
+		{"archives-from-0", &archivesFrom0, "also process the archives listed in this file, separated by NUL characters as by find -print0; '-' for stdin"},
+		{"manifest", &manifestFile, "also process the archives listed in this CSV report of census, each with the codepage it gives; edit the codepage column to correct it, or empty it to use -f"},
+		{"jobs", &batchJobs, "process this many archives at once, each in a process of its own, so that a failing archive does not affect the others; it is also the number of threads they share for decoding, unless -threads is given"},
+		{"o", &overwrite, "overwrite existing files"},
+		{"symlink-policy", &symlinkPolicy, "how to extract symbolic links: auto, link, junction (Windows directories), hardlink, copy, skip, or file (a file containing the target path)"},
+		{"ads", &adsPolicy, "how to extract NTFS alternate data streams, named like file.txt:stream: auto (streams on Windows, sidecars elsewhere, for names whose file is also in the archive), stream, sidecar (a file named file.txt_stream), or skip"},
+		{"mac-forks", &macForksPolicy, "how to extract AppleDouble ._name entries holding Mac resource forks and Finder info, usually under __MACOSX: keep (as named in the archive), auto (restore on macOS, sidecar elsewhere), restore (macOS only), sidecar (._name next to the file), or skip"},
+		{"specials", &specialsPolicy, "how to extract FIFO, device and socket entries: skip, error, or create"},
+		{"backup-existing", &backupExisting, "move overwritten files into a backup directory, keeping their relative paths; use -backup-existing=DIR to choose the directory"},
+		{"k", &keepFileDir, "keep-organized; make a subdirectory of the same name with ZIP file and put files there"},
+		{"dir-data", &dirDataPolicy, "what to do with an entry whose name ends with a slash but that has data: dir (ignore the data), file (write it as a file), or error"},
+		{"k-policy", &keepDirPolicy, "what to do when the subdirectory of -k exists and is not empty: merge, suffix or error"},
+		{"no-lock", &noLock, "do not lock the output directory against other extractions into it"},
+		{"wait", &waitLock, "wait for another extraction into the same output directory to finish, instead of failing"},
+		{"staging", &staging, "extract into a temporary directory and move it into place only when everything is done"},
+		{"sandbox", &useSandbox, "(Linux only) confine all writes of the extraction into the output directory using openat2(), and refuse device, fifo and setuid entries; not with -staging"},
+		{"max-entries", &maxEntries, "refuse archives with more entries than this (0 for no limit)"},
+		{"max-depth", &maxDepth, "refuse entries with more path levels than this (0 for no limit)"},
+		{"read-order", &readOrder, "the order to extract entries in: cd (as listed in the central directory) or offset (as stored in the file, for sequential reading)"},
+		{"source-tz", &sourceTZ, "the time zone the archive was made in, e.g. Asia/Tokyo, for the modification times of entries that have only a DOS timestamp (default the local zone)"},
+		{"dirs-only", &dirsOnly, "create only the directory structure, without the files"},
+		{"update", &updateOnly, "extract only files that do not exist or are older than their entries, replacing the older ones; times within the 2-second precision of DOS timestamps, or an hour apart by daylight saving time, count as the same"},
+		{"since", &sinceArchive, "extract only entries that are new or changed (by name and CRC) since this older version of the archive"},
+		{"small-first", &smallFirst, "extract smaller entries before larger ones"},
+		{"checkpoint", &checkpointFile, "record completed entries in this file, and skip entries it records as completed (for resuming interrupted extractions)"},
+		{"write-retries", &writeRetries, "times to retry creating a file when the system reports it busy, e.g. while a virus scanner checks it"},
+		{"preallocate", &preallocate, "reserve the full size of each file before writing it, to reduce fragmentation and fail early when the disk is full (Linux, macOS, FreeBSD and Windows; ignored elsewhere)"},
+		{"fsync", &fsyncPolicy, "what to fsync for crash durability: never, files (each extracted file), dirs (directories with new entries), or all"},
+		{"zero-copy", &zeroCopy, "copy uncompressed (stored) entries directly from the ZIP file; faster, but their CRC is not verified"},
+		{"threads", &decodeThreads, "threads for decoding a Zstandard-compressed entry (0 for the number of CPUs)"},
+		{"warnings-as-errors", &warningsAsErrors, "fail if there are any warnings, such as unconvertible names, suspicious names, skipped entries or implausible timestamps"},
+		{"keep-going", &keepGoing, "report entries that cannot be extracted and continue with the rest"},
+		{"entry-timeout", &entryTimeout, "give up an entry if reading its data stalls for this long (e.g. 30s; 0 for no timeout)"},
+		{"route", &routeSpec, "put files into subdirectories by extension, e.g. 'jpg,png=images/;txt=docs/'"},
+		{"ascii-slugs", &asciiSlugs, "transliterate output names to ASCII-only names, and write the mapping to " + slugsMapFilename},
+		{"translit", &translitNames, "transliteration schemes for -ascii-slugs and translit, in the order of preference (hepburn: Japanese kana, rr: Korean, iso9: Cyrillic, latin: diacritics, pinyin: Chinese characters; not by default)"},
+		{"fs-names", &fsNames, "when the output directory is on a FAT or NTFS filesystem: warn about names it may not accept, fix them, or off"},
+		{"preset", &presetName, "apply a bundle of naming options: " + presetNames() + "; options given explicitly take precedence"},
+		{"fix-extensions", &fixExtensions, "append the extension of the detected content type to files with a missing or implausible extension; a comma-separated list of types to detect (e.g. jpg,png,pdf), or 'all'"},
+		{"strip-controls", &stripControls, "remove control characters, bidi overrides and zero-width characters from names"},
+		{"width-fold", &widthFold, "convert full-width ASCII letters, digits and symbols to ASCII, and half-width katakana to full-width"},
+		{"windows-names", &windowsNames, "rename Windows reserved names like CON or NUL.txt even when not extracting to Windows or a FAT/NTFS filesystem"},
+		{"transform-cmd", &transformCmd, "external command that renames or skips entries; it reads a JSON request per entry on stdin and writes a JSON response per line"},
+		{"wizard", &wizard, "choose the archive, codepage and destination interactively, with previews of the names"},
+		{"q", &quiet, "suppress messages"},
+		{"lang", &langName, "language of messages: en, ja, ko, zh or ru (default from LANG); the usage, prompts and common messages are translated, and other errors stay in English"},
+		{"explain-names", &explainNames, "print how the output name of each entry was made: decoding, transform, slugs, sanitization and routing"},
+		{"export", &exportFile, "write the names, sizes, dates, CRCs and encodings of the entries to this file instead of extracting; CSV, or XLSX if the name ends with .xlsx"},
+		{"password-list", &passwordList, "try each password in this file, one per line, against the encrypted entries and report which ones open them, instead of extracting"},
+		{"cache-dir", &cacheDir, "when the archive is given as an http or https URL, keep the parts read from it in this directory, by URL and ETag, so that listing or extracting it again does not download them again"},
+		{"list-cache", &listCache, "with -l, cache the listing by the archive contents and options, and print the cached listing next time"},
+		{"convert-content", &convertContent, "convert the content of files with these extensions from -f to -t, e.g. 'txt,csv' ('*' for all files)"},
+		{"scan-text", &scanText, "after extraction, report text files whose contents are not valid UTF-8 or contain replacement characters"},
+		{"stats", &showStats, "print statistics by compression method and by name encoding after extraction"},
+		{"encoding-stats", &encodingStats, "count the codepage used for each archive with non-UTF-8 names, and how it was chosen, in encoding-stats.json in the user configuration directory; nothing is sent anywhere"},
+		{"names-map", &writeMap, "write a " + namesMapFilename + " file recording the raw name, encoding and output path of each extracted entry"},
+		{"encrypt", &encryptEntries, "add: encrypt the new entries with WinZip AES-256; existing entries are copied as they are"},
+		{"password", &entryPassword, "add: the password for -encrypt; other users of the system may see it in the process list"},
+		{"set-comment", &setComment, "comment: set the archive comment, or the comment of the given entry"},
+		{"transcode-comments", &transcodeComments, "comment: convert the archive and entry comments from -f to -t"},
+		{"census-format", &censusFormat, "census: the format of the report, csv or json"},
+		{"list-encodings", &listEncodings, "print the available codepages and their names, and exit"},
+		{"f", &convertFrom, "codepage of filenames in ZIP; 'auto' to detect it from the names"},
+		{"t", &convertTo, "codepage of output filenames. WARNING: change this only if you know exactly what you are doing!"},
+	}
+}
+
+// define the flags of specs on flag.CommandLine, with the current values of
+// their variables as the defaults.
+func registerFlags(specs []flagSpec) {
+	for _, s := range specs {
+		switch v := s.value.(type) {
+		case flag.Value:
+			flag.Var(v, s.name, s.usage)
+		case *bool:
+			flag.BoolVar(v, s.name, *v, s.usage)
+		case *int:
+			flag.IntVar(v, s.name, *v, s.usage)
+		case *string:
+			flag.StringVar(v, s.name, *v, s.usage)
+		case *time.Duration:
+			flag.DurationVar(v, s.name, *v, s.usage)
+		default:
+			panic(fmt.Sprintf("flag -%s: unsupported type %T", s.name, s.value))
+		}
+	}
+}
+
+// print the usage message.
+func printUsage(w io.Writer, prog string) {
+	fmt.Fprint(w, tr("Decompress a ZIP file with non-unicode filenames.\n"))
+	fmt.Fprintf(w, "\n")
+	label := tr("Usage:")
+	for i, c := range commandSpecs {
+		if i == 0 {
+			fmt.Fprintf(w, "%s %s %s\n", label, prog, c.synopsis)
+		} else {
+			fmt.Fprintf(w, "%s %s %s\n", strings.Repeat(" ", displayWidth(label)), prog, c.synopsis)
+		}
+	}
+	fmt.Fprintf(w, "\n")
+	for _, d := range descriptionSpec {
+		fmt.Fprint(w, tr(d))
+	}
+	fmt.Fprintf(w, "\n")
+
+	fmt.Fprint(w, tr("Flags:\n"))
+	flag.VisitAll(func(f *flag.Flag) {
+		printFlag(w, f)
+	})
+	fmt.Fprintf(w, "\n")
+}
+
+// print a flag as flag.PrintDefaults does, with its description in the language of messages
+func printFlag(w io.Writer, f *flag.Flag) {
+	var b strings.Builder
+	name, _ := flag.UnquoteUsage(f)
+	fmt.Fprintf(&b, "  -%s", f.Name)
+	if name != "" {
+		fmt.Fprintf(&b, " %s", name)
+	}
+	if b.Len() <= 4 {
+		b.WriteString("\t")
+	} else {
+		b.WriteString("\n    \t")
+	}
+	b.WriteString(strings.ReplaceAll(trFlag(f.Name, f.Usage), "\n", "\n    \t"))
+	if !isZeroValue(f) {
+		def := f.DefValue
+		if name == "string" {
+			def = fmt.Sprintf("%q", def)
+		}
+		fmt.Fprintf(&b, tr(" (default %s)"), def)
+	}
+	fmt.Fprintln(w, b.String())
+}
+
+// whether the default of a flag is the zero value of its type, which flag.PrintDefaults leaves out
+func isZeroValue(f *flag.Flag) bool {
+	typ := reflect.TypeOf(f.Value)
+	var z reflect.Value
+	if typ.Kind() == reflect.Pointer {
+		z = reflect.New(typ.Elem())
+	} else {
+		z = reflect.Zero(typ)
+	}
+	v, ok := z.Interface().(flag.Value)
+	return ok && f.DefValue == v.String()
+}
+
+// the width of a string on a terminal, counting East Asian wide characters as two columns
+func displayWidth(s string) (n int) {
+	for _, r := range s {
+		n++
+		if r >= 0x1100 && (r <= 0x115f || r >= 0x2e80 && r <= 0xa4cf || r >= 0xac00 && r <= 0xd7a3 ||
+			r >= 0xf900 && r <= 0xfaff || r >= 0xfe30 && r <= 0xfe4f || r >= 0xff00 && r <= 0xff60 || r >= 0xffe0 && r <= 0xffe6) {
+			n++
+		}
+	}
+	return
+}
+
+// escape text for roff.
+func roffEscape(s string) string {
+	s = strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(s)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
+
+// write a man page in roff format, from commandSpecs, descriptionSpec and
+// the flags defined on flag.CommandLine.
+func writeManPage(w io.Writer, prog string) {
+	fmt.Fprintf(w, ".TH %s 1\n", strings.ToUpper(roffEscape(prog)))
+	fmt.Fprintf(w, ".SH NAME\n%s \\- decompress a ZIP file with non-unicode filenames\n", roffEscape(prog))
+
+	fmt.Fprintf(w, ".SH SYNOPSIS\n")
+	for i, c := range commandSpecs {
+		if i > 0 {
+			fmt.Fprintf(w, ".br\n")
+		}
+		fmt.Fprintf(w, ".B %s\n%s\n", roffEscape(prog), roffEscape(c.synopsis))
+	}
+
+	fmt.Fprintf(w, ".SH DESCRIPTION\n")
+	for _, d := range descriptionSpec {
+		fmt.Fprintf(w, ".PP\n%s\n", roffEscape(strings.TrimSuffix(d, "\n")))
+	}
+
+	fmt.Fprintf(w, ".SH COMMANDS\n")
+	for _, c := range commandSpecs {
+		name := c.name
+		if name == "" {
+			name = c.synopsis
+		}
+		fmt.Fprintf(w, ".TP\n.B %s\n%s\n", roffEscape(name), roffEscape(c.summary))
+	}
+
+	fmt.Fprintf(w, ".SH OPTIONS\n")
+	flag.VisitAll(func(f *flag.Flag) {
+		arg, usage := flag.UnquoteUsage(f)
+		fmt.Fprintf(w, ".TP\n.B \\-%s", roffEscape(f.Name))
+		if arg != "" {
+			fmt.Fprintf(w, " \\fI%s\\fR", roffEscape(arg))
+		}
+		fmt.Fprintf(w, "\n%s", roffEscape(usage))
+		if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "0" && f.DefValue != "0s" {
+			fmt.Fprintf(w, " (default %s)", roffEscape(f.DefValue))
+		}
+		fmt.Fprintf(w, "\n")
+	})
+}
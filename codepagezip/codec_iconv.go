@@ -0,0 +1,55 @@
+//go:build iconv
+
+package codepagezip
+
+import (
+	"io"
+	"os"
+	"os/exec"
+
+	iconv "github.com/djimenez/iconv-go"
+)
+
+// Backend names the library converting names and contents between codepages.
+const Backend = "iconv"
+
+// A Converter converts strings from one codepage to another.
+type Converter struct {
+	c *iconv.Converter
+}
+
+// NewConverter returns a Converter from the codepage from to the codepage to.
+func NewConverter(from, to string) (*Converter, error) {
+	c, err := iconv.NewConverter(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return &Converter{c}, nil
+}
+
+// ConvertString converts a string.
+func (c *Converter) ConvertString(s string) (string, error) {
+	return c.c.ConvertString(s)
+}
+
+// Close releases the resources of the converter.
+func (c *Converter) Close() {
+	c.c.Close()
+}
+
+// NewConvertingReader returns a reader converting the data of r from one codepage to another.
+func NewConvertingReader(r io.Reader, from, to string) (io.Reader, error) {
+	c, err := iconv.NewConverter(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return iconv.NewReaderFromConverter(r, c), nil
+}
+
+// WriteEncodings writes the codepages iconv accepts, as listed by iconv --list.
+func WriteEncodings(w io.Writer) error {
+	cmd := exec.Command("iconv", "--list")
+	cmd.Stdout = w
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
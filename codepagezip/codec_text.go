@@ -0,0 +1,189 @@
+//go:build !iconv
+
+package codepagezip
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// Backend names the library converting names and contents between codepages.
+const Backend = "golang.org/x/text"
+
+// the codepages and their names, the first of which is listed as the main one.
+// iconv names are included so that the same names work with both backends.
+// Other WHATWG and IANA names are accepted too.
+var encodingTable = []struct {
+	enc   encoding.Encoding
+	names []string
+}{
+	{unicode.UTF8, []string{"UTF-8", "UTF8"}},
+	{japanese.ShiftJIS, []string{"SHIFT_JIS", "SJIS", "CP932", "MS932", "WINDOWS-31J", "MS_KANJI"}},
+	{japanese.EUCJP, []string{"EUC-JP", "EUCJP"}},
+	{japanese.ISO2022JP, []string{"ISO-2022-JP", "CSISO2022JP"}},
+	{korean.EUCKR, []string{"EUC-KR", "EUCKR", "CP949", "UHC", "WINDOWS-949"}},
+	{simplifiedchinese.GBK, []string{"GBK", "CP936", "GB2312", "EUC-CN", "WINDOWS-936"}},
+	{simplifiedchinese.GB18030, []string{"GB18030"}},
+	{simplifiedchinese.HZGB2312, []string{"HZ-GB-2312", "HZ"}},
+	{traditionalchinese.Big5, []string{"BIG5", "BIG-5", "CP950", "BIG5-HKSCS"}},
+	{charmap.CodePage437, []string{"CP437", "IBM437", "437"}},
+	{charmap.CodePage850, []string{"CP850", "IBM850", "850"}},
+	{charmap.CodePage852, []string{"CP852", "IBM852", "852"}},
+	{charmap.CodePage855, []string{"CP855", "IBM855", "855"}},
+	{charmap.CodePage858, []string{"CP858", "IBM858", "858"}},
+	{charmap.CodePage860, []string{"CP860", "IBM860", "860"}},
+	{charmap.CodePage862, []string{"CP862", "IBM862", "862"}},
+	{charmap.CodePage863, []string{"CP863", "IBM863", "863"}},
+	{charmap.CodePage865, []string{"CP865", "IBM865", "865"}},
+	{charmap.CodePage866, []string{"CP866", "IBM866", "866"}},
+	{charmap.Windows874, []string{"CP874", "WINDOWS-874", "TIS-620"}},
+	{charmap.Windows1250, []string{"CP1250", "WINDOWS-1250"}},
+	{charmap.Windows1251, []string{"CP1251", "WINDOWS-1251"}},
+	{charmap.Windows1252, []string{"CP1252", "WINDOWS-1252"}},
+	{charmap.Windows1253, []string{"CP1253", "WINDOWS-1253"}},
+	{charmap.Windows1254, []string{"CP1254", "WINDOWS-1254"}},
+	{charmap.Windows1255, []string{"CP1255", "WINDOWS-1255"}},
+	{charmap.Windows1256, []string{"CP1256", "WINDOWS-1256"}},
+	{charmap.Windows1257, []string{"CP1257", "WINDOWS-1257"}},
+	{charmap.Windows1258, []string{"CP1258", "WINDOWS-1258"}},
+	{charmap.KOI8R, []string{"KOI8-R"}},
+	{charmap.KOI8U, []string{"KOI8-U"}},
+	{charmap.Macintosh, []string{"MACINTOSH", "MAC", "MACROMAN"}},
+	{charmap.MacintoshCyrillic, []string{"MACCYRILLIC", "X-MAC-CYRILLIC"}},
+	{charmap.ISO8859_1, []string{"ISO-8859-1", "LATIN1"}},
+	{charmap.ISO8859_2, []string{"ISO-8859-2", "LATIN2"}},
+	{charmap.ISO8859_3, []string{"ISO-8859-3", "LATIN3"}},
+	{charmap.ISO8859_4, []string{"ISO-8859-4", "LATIN4"}},
+	{charmap.ISO8859_5, []string{"ISO-8859-5", "CYRILLIC"}},
+	{charmap.ISO8859_6, []string{"ISO-8859-6", "ARABIC"}},
+	{charmap.ISO8859_7, []string{"ISO-8859-7", "GREEK"}},
+	{charmap.ISO8859_8, []string{"ISO-8859-8", "HEBREW"}},
+	{charmap.ISO8859_9, []string{"ISO-8859-9", "LATIN5"}},
+	{charmap.ISO8859_10, []string{"ISO-8859-10", "LATIN6"}},
+	{charmap.ISO8859_13, []string{"ISO-8859-13", "LATIN7"}},
+	{charmap.ISO8859_14, []string{"ISO-8859-14", "LATIN8"}},
+	{charmap.ISO8859_15, []string{"ISO-8859-15", "LATIN-9"}},
+	{charmap.ISO8859_16, []string{"ISO-8859-16", "LATIN10"}},
+	{unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), []string{"UTF-16BE"}},
+	{unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), []string{"UTF-16LE"}},
+}
+
+var errInvalidSequence = errors.New("invalid or incomplete multibyte sequence")
+
+// compare codepage names regardless of case, hyphens and underscores
+func normalizeEncodingName(name string) string {
+	return strings.NewReplacer("-", "", "_", "", " ", "").Replace(strings.ToUpper(name))
+}
+
+// find a codepage by name
+func lookupEncoding(name string) (encoding.Encoding, error) {
+	key := normalizeEncodingName(name)
+	for _, e := range encodingTable {
+		for _, n := range e.names {
+			if normalizeEncodingName(n) == key {
+				return e.enc, nil
+			}
+		}
+	}
+	if enc, err := htmlindex.Get(name); err == nil {
+		return enc, nil
+	}
+	if enc, err := ianaindex.IANA.Encoding(name); err == nil && enc != nil {
+		return enc, nil
+	}
+	return nil, fmt.Errorf("unknown codepage %q (see -list-encodings)", name)
+}
+
+// A Converter converts strings from one codepage to another, through UTF-8.
+type Converter struct {
+	dec *encoding.Decoder // nil when converting from UTF-8
+	enc *encoding.Encoder // nil when converting to UTF-8
+}
+
+// NewConverter returns a Converter from the codepage from to the codepage to.
+func NewConverter(from, to string) (*Converter, error) {
+	fromEnc, err := lookupEncoding(from)
+	if err != nil {
+		return nil, err
+	}
+	toEnc, err := lookupEncoding(to)
+	if err != nil {
+		return nil, err
+	}
+	c := &Converter{}
+	if fromEnc != unicode.UTF8 {
+		c.dec = fromEnc.NewDecoder()
+	}
+	if toEnc != unicode.UTF8 {
+		c.enc = toEnc.NewEncoder()
+	}
+	return c, nil
+}
+
+// ConvertString converts a string. Bytes invalid in the source codepage are an error, as with iconv.
+func (c *Converter) ConvertString(s string) (string, error) {
+	if c.dec == nil {
+		if !utf8.ValidString(s) {
+			return "", errInvalidSequence
+		}
+	} else {
+		u, err := c.dec.String(s)
+		if err != nil {
+			return "", err
+		}
+		if strings.ContainsRune(u, utf8.RuneError) { // the decoders replace invalid bytes
+			return "", errInvalidSequence
+		}
+		s = u
+	}
+	if c.enc == nil {
+		return s, nil
+	}
+	return c.enc.String(s)
+}
+
+// Close releases the resources of the converter.
+func (c *Converter) Close() {}
+
+// NewConvertingReader returns a reader converting the data of r from one codepage to another.
+// Bytes invalid in the source codepage are replaced by U+FFFD.
+func NewConvertingReader(r io.Reader, from, to string) (io.Reader, error) {
+	c, err := NewConverter(from, to)
+	if err != nil {
+		return nil, err
+	}
+	var ts []transform.Transformer
+	if c.dec != nil {
+		ts = append(ts, c.dec)
+	}
+	if c.enc != nil {
+		ts = append(ts, c.enc)
+	}
+	if len(ts) == 0 {
+		return r, nil
+	}
+	return transform.NewReader(r, transform.Chain(ts...)), nil
+}
+
+// WriteEncodings writes the codepages and their names, one codepage per line.
+func WriteEncodings(w io.Writer) error {
+	for _, e := range encodingTable {
+		fmt.Fprintf(w, "%s\n", strings.Join(e.names, ", "))
+	}
+	fmt.Fprintf(w, "\nOther WHATWG and IANA names of these codepages are accepted too.\n")
+	return nil
+}
@@ -0,0 +1,262 @@
+// Package codepagezip reads ZIP archives whose file names are in legacy codepages,
+// like Shift_JIS or CP866, converting the names to UTF-8.
+//
+// Names are converted with golang.org/x/text, or with iconv when built with the iconv tag.
+package codepagezip
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UTF8 is the name of the UTF-8 codepage.
+const UTF8 = "utf-8"
+
+// the general purpose flag telling that the name is in UTF-8
+const flagEFS = 0x800
+
+// NameEncoding returns the codepage of the name of f: UTF-8 if archive/zip finds
+// the name flagged or valid as UTF-8, or encoding otherwise.
+func NameEncoding(f *zip.File, encoding string) string {
+	if !f.NonUTF8 { // Note that EFS flag checking is done in archive/zip package
+		return UTF8
+	}
+	return encoding
+}
+
+// ConvertString converts a string from one codepage to another.
+func ConvertString(s, from, to string) (string, error) {
+	c, err := NewConverter(from, to)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+	return c.ConvertString(s)
+}
+
+// ConvertName converts the name of f from its codepage, encoding if it is not UTF-8, to the codepage to.
+func ConvertName(f *zip.File, encoding, to string) (string, error) {
+	name, err := ConvertString(f.Name, NameEncoding(f, encoding), to) // Note that it's safe to store non-UTF8 bytes in Go string, because it's internally just a []byte
+	if err != nil {
+		return "", fmt.Errorf("converting from %s to %s: %w", encoding, to, err)
+	}
+	return name, nil
+}
+
+// convert the name of f to UTF-8, replacing the bytes invalid in its codepage with U+FFFD
+func lossyName(f *zip.File, encoding string) (string, error) {
+	r, err := NewConvertingReader(strings.NewReader(f.Name), NameEncoding(f, encoding), UTF8)
+	if err != nil {
+		return "", err
+	}
+	b, err := io.ReadAll(r)
+	return string(b), err
+}
+
+// A Reader reads a ZIP archive, converting the names of its entries to UTF-8.
+type Reader struct {
+	*zip.Reader
+
+	// Encoding is the codepage of the names that are not in UTF-8.
+	Encoding string
+
+	// NameHook, if not nil, is called with each converted name.
+	// It returns the name to use instead, or skip to leave the entry out.
+	NameHook func(f *zip.File, name string) (newName string, skip bool)
+
+	// Location is the time zone DOS timestamps were recorded in; nil for the local zone.
+	Location *time.Location
+
+	// Warn, if not nil, is called with each problem that does not stop reading or extracting an entry:
+	// a name that leaves the output directory, or that is not valid in its codepage, when the name is
+	// converted, and an entry not extracted or with an implausible time, when it is extracted.
+	// With Warn, a name that is not valid is converted with U+FFFD for the invalid bytes, instead of
+	// being an error, unless built with iconv, which does not replace them.
+	// The warnings of a name are given once, as it is converted once.
+	// To fail on warnings, as -warnings-as-errors does, record them and check after ExtractTo.
+	Warn func(Warning)
+
+	mu      sync.Mutex
+	names   map[*zip.File]converted // the names converted so far
+	index   map[string]*zip.File    // the entries by converted name, for OpenEntry
+	scanned int                     // the number of entries of r.File added to index
+	pending []Warning               // warnings to give to Warn when mu is released
+}
+
+// a memoized result of Name
+type converted struct {
+	name string
+	skip bool
+	err  error
+}
+
+// A ReadCloser is a Reader of a file, which must be closed.
+type ReadCloser struct {
+	Reader
+	zr *zip.ReadCloser
+}
+
+// OpenReader opens the ZIP file of the given name, whose names not in UTF-8 are in encoding.
+func OpenReader(name, encoding string) (*ReadCloser, error) {
+	zr, err := zip.OpenReader(name)
+	if err != nil {
+		return nil, err
+	}
+	return &ReadCloser{Reader{Reader: &zr.Reader, Encoding: encoding}, zr}, nil
+}
+
+// Close closes the ZIP file.
+func (rc *ReadCloser) Close() error {
+	return rc.zr.Close()
+}
+
+// NewReader returns a Reader reading from r, which is assumed to have the given size in bytes.
+func NewReader(r io.ReaderAt, size int64, encoding string) (*Reader, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{Reader: zr, Encoding: encoding}, nil
+}
+
+// An Entry is a file in the archive and its converted name.
+type Entry struct {
+	File *zip.File
+	Name string
+}
+
+// OpenRaw opens the entry for reading its content as stored, compressed and maybe encrypted,
+// without decompressing it. The header returned is a copy of the entry's, with the method, the CRC-32
+// and the sizes, under the converted name flagged as UTF-8, so that the entry can be copied into
+// another archive under that name with zip.Writer.CreateRaw.
+func (e Entry) OpenRaw() (io.Reader, *zip.FileHeader, error) {
+	r, err := e.File.OpenRaw()
+	if err != nil {
+		return nil, nil, err
+	}
+	fh := e.File.FileHeader
+	fh.Name = e.Name
+	fh.NonUTF8 = false
+	fh.Flags |= flagEFS // CreateRaw does not set it
+	fh.Extra = stripExtra(fh.Extra, extraUnicodePath)
+	return r, &fh, nil
+}
+
+// Name returns the converted name of f, after NameHook. skip reports that NameHook left it out.
+// Each name is converted once, when it is first asked for; Encoding and NameHook must not change after it.
+func (r *Reader) Name(f *zip.File) (name string, skip bool, err error) {
+	r.mu.Lock()
+	defer r.unlock()
+	c := r.name(f)
+	return c.name, c.skip, c.err
+}
+
+// convert the name of f, or get it converted before; r.mu must be held
+func (r *Reader) name(f *zip.File) converted {
+	if c, ok := r.names[f]; ok {
+		return c
+	}
+	var c converted
+	name, err := ConvertName(f, r.Encoding, UTF8)
+	if err != nil && r.Warn != nil {
+		name, err = lossyName(f, r.Encoding)
+		if err == nil {
+			r.warn(WarnLossy, f, name, "the name is not valid in %s; invalid bytes are replaced", NameEncoding(f, r.Encoding))
+		}
+	}
+	if err != nil {
+		c.err = fmt.Errorf("%q: %w", f.Name, err)
+	} else if r.NameHook != nil {
+		c.name, c.skip = r.NameHook(f, name)
+	} else {
+		c.name = name
+	}
+	if err == nil && !c.skip && SuspiciousName(c.name) {
+		r.warn(WarnName, f, c.name, "the name leaves the output directory; it is extracted as %s", SanitizePath(c.name))
+	}
+	if r.names == nil {
+		r.names = make(map[*zip.File]converted)
+	}
+	r.names[f] = c
+	return c
+}
+
+// List returns the entries of the archive with their converted names, except those NameHook leaves out.
+func (r *Reader) List() ([]Entry, error) {
+	return r.ListPage(0, len(r.File))
+}
+
+// ListPage returns the entries of r.File[offset:offset+limit] with their converted names, except those
+// NameHook leaves out, converting only the names of that page. Pages follow the order of the central directory,
+// so the next one starts at offset+limit, and a page has fewer than limit entries when some are left out.
+// It returns no entries once offset reaches len(r.File).
+func (r *Reader) ListPage(offset, limit int) ([]Entry, error) {
+	if offset < 0 || limit < 0 {
+		return nil, fmt.Errorf("invalid page: offset %d, limit %d", offset, limit)
+	}
+	offset = min(offset, len(r.File))
+	end := offset + min(limit, len(r.File)-offset)
+	entries := make([]Entry, 0, end-offset)
+	for _, f := range r.File[offset:end] {
+		name, skip, err := r.Name(f)
+		if err != nil {
+			return nil, err
+		}
+		if !skip {
+			entries = append(entries, Entry{f, name})
+		}
+	}
+	return entries, nil
+}
+
+// Lookup returns the entry of the given converted name; the first one if several have it.
+// Names are converted in the order of the central directory only until the entry is found,
+// so looking up one entry of a large archive does not convert all of their names,
+// and the names converted are kept for later lookups.
+// Entries whose names cannot be converted are not found.
+func (r *Reader) Lookup(name string) (*zip.File, bool) {
+	r.mu.Lock()
+	defer r.unlock()
+	if f, ok := r.index[name]; ok {
+		return f, true
+	}
+	if r.index == nil {
+		r.index = make(map[string]*zip.File)
+	}
+	for r.scanned < len(r.File) {
+		f := r.File[r.scanned]
+		r.scanned++
+		c := r.name(f)
+		if c.err != nil || c.skip {
+			continue
+		}
+		if _, ok := r.index[c.name]; ok {
+			continue
+		}
+		r.index[c.name] = f
+		if c.name == name {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// OpenEntry opens the entry of the given converted name for reading its content, as found by Lookup.
+// It is not named Open, which the embedded zip.Reader has for its fs.FS on the raw names.
+func (r *Reader) OpenEntry(name string) (io.ReadCloser, error) {
+	f, ok := r.Lookup(name)
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return f.Open()
+}
+
+// ModTime returns the modification time of f, with DOS timestamps in r.Location.
+func (r *Reader) ModTime(f *zip.File) time.Time {
+	return ModTime(f, r.Location)
+}
@@ -0,0 +1,530 @@
+package codepagezip
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConvertString(t *testing.T) {
+	tests := []struct {
+		s, from, to, want string
+	}{
+		{"\x83e\x83X\x83g.txt", "SHIFT-JIS", UTF8, "テスト.txt"},
+		{"\xc7\xd1\xb1\xdb", "EUC-KR", UTF8, "한글"},
+		{"\x8f\xe0\xa8\xa2\xa5\xe2", "CP866", UTF8, "Привет"},
+		{"テスト", UTF8, "SHIFT-JIS", "\x83e\x83X\x83g"},
+		{"plain ascii", "CP932", UTF8, "plain ascii"},
+		{"", "SHIFT-JIS", UTF8, ""},
+	}
+	for _, tt := range tests {
+		got, err := ConvertString(tt.s, tt.from, tt.to)
+		if err != nil {
+			t.Errorf("ConvertString(%q, %s, %s): %v", tt.s, tt.from, tt.to, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ConvertString(%q, %s, %s) = %q, want %q", tt.s, tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestConvertStringErrors(t *testing.T) {
+	if _, err := ConvertString("x", "no-such-codepage", UTF8); err == nil {
+		t.Error("an unknown codepage was accepted")
+	}
+	if _, err := ConvertString("\x83", "SHIFT-JIS", UTF8); err == nil {
+		t.Error("a truncated Shift_JIS character was accepted")
+	}
+}
+
+func TestSanitizePath(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"dir/file.txt", "dir/file.txt"},
+		{"dir/", "dir"},
+		{"a/./b//c", "a/b/c"},
+		{"../../etc/passwd", "etc/passwd"},
+		{"a/../../b", "a/b"},
+		{"/etc/passwd", "etc/passwd"},
+		{`C:\Windows\win.ini`, "Windows/win.ini"},
+		{`..\..\x`, "x"},
+		{"a\x00b/c\x1fd", "a_b/c_d"},
+		{"..", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := SanitizePath(tt.name); got != tt.want {
+			t.Errorf("SanitizePath(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizePathContainment(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "out")
+	names := []string{
+		"../x", "../../../../x", "/x", "//server/share/x", `\\server\share\x`, `C:\x`, "C:x",
+		"a/../../x", `a\..\..\x`, "./../x", "..", ".", "...", "a/..", "\x00/../x",
+	}
+	for _, name := range names {
+		p := filepath.Join(root, filepath.FromSlash(SanitizePath(name)))
+		rel, err := filepath.Rel(root, p)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			t.Errorf("%q is extracted to %s, outside of %s", name, p, root)
+		}
+	}
+}
+
+func TestSanitizeComponent(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"file.txt", "file.txt"},
+		{"a/b", "a_b"},
+		{`a\b`, "a_b"},
+		{"..", "_"},
+		{".", "_"},
+		{"", "_"},
+	}
+	for _, tt := range tests {
+		if got := SanitizeComponent(tt.name); got != tt.want {
+			t.Errorf("SanitizeComponent(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReader(t *testing.T) {
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for name, content := range map[string]string{
+		"\x83e\x83X\x83g/\x93\xfa\x96{\x8c\xea.txt": "nihongo",
+		"ascii.txt": "ascii",
+	} {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, NonUTF8: true, Method: zip.Store})
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, content)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()), "SHIFT-JIS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.NameHook = func(f *zip.File, name string) (string, bool) {
+		return name, name == "ascii.txt"
+	}
+	entries, err := r.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name != "テスト/日本語.txt" {
+		t.Fatalf("List() = %v, want only テスト/日本語.txt", entries)
+	}
+
+	rc, err := r.OpenEntry("テスト/日本語.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil || string(b) != "nihongo" {
+		t.Errorf("OpenEntry(テスト/日本語.txt) read %q, %v", b, err)
+	}
+	if _, err := r.OpenEntry("ascii.txt"); err == nil {
+		t.Error("an entry left out by NameHook was opened")
+	}
+}
+
+// a lookup converts names only until the entry is found, and each name only once
+func TestLookup(t *testing.T) {
+	var files [][2]string
+	for i := 0; i < 100; i++ {
+		files = append(files, [2]string{fmt.Sprintf("\x83e%02d.txt", i), ""})
+	}
+	zr := makeZip(t, files)
+	r, err := NewReader(zr, zr.Size(), "SHIFT-JIS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := map[string]int{}
+	r.NameHook = func(f *zip.File, name string) (string, bool) {
+		calls[name]++
+		return name, false
+	}
+	if f, ok := r.Lookup("テ02.txt"); !ok || f != r.File[2] {
+		t.Fatalf("Lookup(テ02.txt) = %v, %v", f, ok)
+	}
+	if len(calls) != 3 {
+		t.Errorf("%d names converted for the third entry", len(calls))
+	}
+	for _, name := range []string{"テ01.txt", "テ50.txt", "テ02.txt"} {
+		if _, ok := r.Lookup(name); !ok {
+			t.Errorf("Lookup(%s) failed", name)
+		}
+	}
+	if len(calls) != 51 {
+		t.Errorf("%d names converted up to the 51st entry", len(calls))
+	}
+	if _, ok := r.Lookup("none"); ok {
+		t.Error("a missing name was found")
+	}
+	if _, err := r.List(); err != nil {
+		t.Fatal(err)
+	}
+	for name, n := range calls {
+		if n != 1 {
+			t.Errorf("%s converted %d times", name, n)
+		}
+	}
+}
+
+func TestListPage(t *testing.T) {
+	var files [][2]string
+	for i := 0; i < 10; i++ {
+		files = append(files, [2]string{fmt.Sprintf("\x83e%d.txt", i), ""})
+	}
+	zr := makeZip(t, files)
+	r, err := NewReader(zr, zr.Size(), "SHIFT-JIS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	converted := 0
+	r.NameHook = func(f *zip.File, name string) (string, bool) {
+		converted++
+		return name, name == "テ4.txt"
+	}
+	tests := []struct {
+		offset, limit int
+		want          string
+	}{
+		{0, 3, "テ0.txt テ1.txt テ2.txt"},
+		{3, 3, "テ3.txt テ5.txt"},
+		{9, 3, "テ9.txt"},
+		{10, 3, ""},
+		{20, 3, ""},
+		{2, 0, ""},
+	}
+	for _, tt := range tests {
+		entries, err := r.ListPage(tt.offset, tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name)
+		}
+		if got := strings.Join(names, " "); got != tt.want {
+			t.Errorf("ListPage(%d, %d) = %q, want %q", tt.offset, tt.limit, got, tt.want)
+		}
+	}
+	if converted != 7 {
+		t.Errorf("%d names converted for 7 entries", converted)
+	}
+	if _, err := r.ListPage(-1, 3); err == nil {
+		t.Error("a negative offset was accepted")
+	}
+}
+
+// an entry copied raw keeps its content under the converted name
+func TestOpenRaw(t *testing.T) {
+	content := strings.Repeat("nihongo ", 100)
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: "\x93\xfa\x96{\x8c\xea.txt", NonUTF8: true, Method: zip.Deflate})
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(w, content)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()), "SHIFT-JIS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := r.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, fh, err := entries[0].OpenRaw()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fh.Method != zip.Deflate || fh.CRC32 != r.File[0].CRC32 || fh.CompressedSize64 >= uint64(len(content)) {
+		t.Errorf("OpenRaw() header: method %d, CRC-32 %08x, compressed size %d", fh.Method, fh.CRC32, fh.CompressedSize64)
+	}
+
+	out := &bytes.Buffer{}
+	zw = zip.NewWriter(out)
+	w, err = zw.CreateRaw(fh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(w, raw); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := zr.File[0]
+	if f.Name != "日本語.txt" || f.NonUTF8 {
+		t.Errorf("copied as %q, NonUTF8 %v", f.Name, f.NonUTF8)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil || string(b) != content {
+		t.Errorf("copied content %q, %v", b, err)
+	}
+}
+
+// make an archive of raw names and contents; names ending with / are directories, and @ before a content makes a symlink
+func makeZip(t *testing.T, files [][2]string) *bytes.Reader {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for _, nc := range files {
+		fh := &zip.FileHeader{Name: nc[0], NonUTF8: true, Method: zip.Store}
+		content := nc[1]
+		if strings.HasPrefix(content, "@") {
+			fh.SetMode(fs.ModeSymlink | 0777)
+			content = content[1:]
+		}
+		w, err := zw.CreateHeader(fh)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, content)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestExtractAll(t *testing.T) {
+	zr := makeZip(t, [][2]string{
+		{"\x83e\x83X\x83g/", ""},
+		{"\x83e\x83X\x83g/a.txt", "a"},
+		{"../escape.txt", "e"},
+		{"link", "@/etc/passwd"},
+		{"linked/b.txt", "b"},
+	})
+	r, err := NewReader(zr, zr.Size(), "SHIFT-JIS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp := t.TempDir()
+	dir := filepath.Join(tmp, "out")
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	// a link made before must not be written through
+	if err := os.Symlink(tmp, filepath.Join(dir, "linked")); err != nil {
+		t.Skip("cannot make symbolic links:", err)
+	}
+	if err := r.ExtractAll(dir); err == nil {
+		t.Error("writing through a symbolic link was accepted")
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "b.txt")); err == nil {
+		t.Error("b.txt was written through a symbolic link")
+	}
+
+	for name, want := range map[string]string{"テスト/a.txt": "a", "escape.txt": "e"} {
+		b, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil || string(b) != want {
+			t.Errorf("%s: %q, %v", name, b, err)
+		}
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "link")); !os.IsNotExist(err) {
+		t.Errorf("a symbolic link entry was extracted: %v", err)
+	}
+}
+
+// a WriteFS recording what is made in it
+type recordFS []string
+
+func (r *recordFS) MkdirAll(name string, perm fs.FileMode) error {
+	*r = append(*r, "mkdir "+name)
+	return nil
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+func (r *recordFS) Create(name string, perm fs.FileMode) (io.WriteCloser, error) {
+	*r = append(*r, "create "+name)
+	return nopCloser{io.Discard}, nil
+}
+
+func TestExtractTo(t *testing.T) {
+	zr := makeZip(t, [][2]string{
+		{"\x83e\x83X\x83g/", ""},
+		{"\x83e\x83X\x83g/a.txt", "a"},
+		{"/abs/../b.txt", "b"},
+		{"link", "@a"},
+	})
+	r, err := NewReader(zr, zr.Size(), "SHIFT-JIS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got recordFS
+	if err := r.ExtractTo(&got); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"mkdir テスト", "mkdir テスト", "create テスト/a.txt", "mkdir abs", "create abs/b.txt"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("ExtractTo made %q, want %q", got, want)
+	}
+
+	for _, name := range []string{"../x", "/x", "a/./b", ""} {
+		if _, err := DirFS(t.TempDir()).Create(name, 0666); err == nil {
+			t.Errorf("DirFS accepted %q", name)
+		}
+	}
+}
+
+func TestMemFS(t *testing.T) {
+	zr := makeZip(t, [][2]string{
+		{"\x83e\x83X\x83g/a.txt", "a"},
+		{"b/c/d.txt", "d"},
+		{"e.txt", "e"},
+	})
+	r, err := NewReader(zr, zr.Size(), "SHIFT-JIS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := MemFS{}
+	if err := r.ExtractTo(m); err != nil {
+		t.Fatal(err)
+	}
+	if diff := m.Diff(map[string]string{
+		"テスト/":      "",
+		"テスト/a.txt": "a",
+		"b/":        "",
+		"b/c/":      "",
+		"b/c/d.txt": "d",
+		"e.txt":     "e",
+	}); diff != nil {
+		t.Errorf("extracted tree differs: %q", diff)
+	}
+
+	diff := m.Diff(map[string]string{"テスト/a.txt": "x", "f.txt": "f"})
+	want := []string{"b/: unexpected", "b/c/: unexpected", "b/c/d.txt: unexpected", "e.txt: unexpected",
+		`f.txt: missing`, `テスト/: unexpected`, `テスト/a.txt: "a", want "x"`}
+	if strings.Join(diff, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Diff() = %q, want %q", diff, want)
+	}
+	if err := m.MkdirAll("e.txt/f", 0777); err == nil {
+		t.Error("a directory was made over a file")
+	}
+}
+
+func TestTransforms(t *testing.T) {
+	zr := makeZip(t, [][2]string{
+		{"\x83e\x83X\x83g/", ""},
+		{"\x83e\x83X\x83g/a.txt", "\x93\xfa\x96{\x8c\xea"},
+		{"\x83e\x83X\x83g/b.bin", "\x93\xfa"},
+		{"c.dat", "\x93\xfa"},
+	})
+	r, err := NewReader(zr, zr.Size(), "SHIFT-JIS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	upper := func(f *zip.File, name string, content io.Reader) (string, io.Reader, error) {
+		return strings.ToUpper(name), content, nil
+	}
+	m := MemFS{}
+	err = r.ExtractTo(m,
+		Filter(func(name string) bool { return !strings.HasSuffix(name, ".bin") }),
+		TranscodeContent("*.txt", "SHIFT-JIS", UTF8),
+		upper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := m.Diff(map[string]string{
+		"テスト/":      "",
+		"テスト/A.TXT": "日本語",
+		"C.DAT":     "\x93\xfa",
+	}); diff != nil {
+		t.Errorf("extracted tree differs: %q", diff)
+	}
+}
+
+func TestWarn(t *testing.T) {
+	if Backend != "golang.org/x/text" {
+		t.Skip("iconv does not replace invalid bytes")
+	}
+	zr := makeZip(t, [][2]string{
+		{"\x83e\x83X\x83g.txt", "a"},
+		{"../up.txt", "u"},
+		{"bad\x83.txt", "b"},
+		{"link", "@a"},
+	})
+	r, err := NewReader(zr, zr.Size(), "SHIFT-JIS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.List(); err == nil {
+		t.Fatal("a name not valid in the codepage was accepted without Warn")
+	}
+
+	r, err = NewReader(zr, zr.Size(), "SHIFT-JIS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	r.Warn = func(w Warning) {
+		r.Lookup(w.Name) // Warn may use the Reader
+		got = append(got, w.Kind+" "+w.Name)
+	}
+	m := MemFS{}
+	if err := r.ExtractTo(m); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"name ../up.txt", "lossy bad\ufffd.txt", "skipped link"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("warnings %q, want %q", got, want)
+	}
+	if _, ok := m["up.txt"]; !ok {
+		t.Errorf("../up.txt was not extracted as up.txt: %v", m)
+	}
+}
+
+func TestHasModTime(t *testing.T) {
+	extTime := []byte{0x55, 0x54, 5, 0, 1, 0, 0, 0, 0}
+	tests := []struct {
+		date  uint16
+		extra []byte
+		want  bool
+	}{
+		{0, nil, false},
+		{0x21, nil, true}, // 1980-01-01
+		{0, extTime, true},
+	}
+	for _, tt := range tests {
+		f := &zip.File{FileHeader: zip.FileHeader{ModifiedDate: tt.date, Extra: tt.extra}}
+		if got := HasModTime(f); got != tt.want {
+			t.Errorf("HasModTime(date %#x, extra %x) = %v, want %v", tt.date, tt.extra, got, tt.want)
+		}
+	}
+}
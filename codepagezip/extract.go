@@ -0,0 +1,214 @@
+package codepagezip
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// A WriteFS is a file system ExtractTo writes into, like a directory on disk, an afero.Fs
+// or a billy.Filesystem behind a small adapter, or a MemFS.
+// Names are slash-separated relative paths, valid as fs.ValidPath requires.
+type WriteFS interface {
+	// MkdirAll makes a directory and its parents, doing nothing for those that exist.
+	MkdirAll(name string, perm fs.FileMode) error
+
+	// Create makes or truncates a file in an existing directory for writing its content.
+	Create(name string, perm fs.FileMode) (io.WriteCloser, error)
+}
+
+// A ChtimesFS is a WriteFS that can set the times of its files.
+// ExtractTo restores modification times only in a ChtimesFS.
+type ChtimesFS interface {
+	WriteFS
+	Chtimes(name string, atime, mtime time.Time) error
+}
+
+// ExtractAll extracts the entries of the archive into dir, as ExtractTo into DirFS(dir).
+func (r *Reader) ExtractAll(dir string, transforms ...Transform) error {
+	return r.ExtractTo(DirFS(dir), transforms...)
+}
+
+// ExtractTo extracts the entries of the archive into dst under their converted names, after NameHook
+// and the transforms. Names are made safe with SanitizePath, and existing files are replaced.
+// Only files and directories are extracted; symbolic links and special files are skipped,
+// as the package does not make them.
+func (r *Reader) ExtractTo(dst WriteFS, transforms ...Transform) error {
+	entries, err := r.List()
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		err = r.extract(dst, e, transforms)
+		if err != nil {
+			return fmt.Errorf("%s: %w", e.Name, err)
+		}
+	}
+	return nil
+}
+
+// extract an entry into dst
+func (r *Reader) extract(dst WriteFS, e Entry, transforms []Transform) error {
+	mode := e.File.Mode()
+	if !mode.IsRegular() && !mode.IsDir() {
+		r.emit(WarnSkipped, e, "%s is not extracted", fileType(mode))
+		return nil
+	}
+	var content io.Reader
+	if mode.IsRegular() {
+		rc, err := e.File.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		content = rc
+	}
+	name := e.Name
+	for _, t := range transforms {
+		var err error
+		name, content, err = t(e.File, name, content)
+		if err != nil || name == "" {
+			return err
+		}
+	}
+	name = SanitizePath(name)
+	if name == "" {
+		r.emit(WarnSkipped, e, "the name is empty once made safe; the entry is not extracted")
+		return nil
+	}
+	parent := path.Dir(name)
+	if mode.IsDir() {
+		parent = name
+	}
+	err := dst.MkdirAll(parent, 0777)
+	if err != nil || mode.IsDir() {
+		return err
+	}
+
+	perm := fs.FileMode(0666)
+	if mode&0111 != 0 {
+		perm = 0777
+	}
+	w, err := dst.Create(name, perm)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, content)
+	if e := w.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return err
+	}
+	cfs, ok := dst.(ChtimesFS)
+	if !ok || !HasModTime(e.File) {
+		return nil
+	}
+	t := r.ModTime(e.File)
+	if SkewedTime(t) {
+		r.emit(WarnTimestamp, e, "implausible modification time %s", t.Format(time.RFC3339))
+	}
+	return cfs.Chtimes(name, t, t)
+}
+
+// give a warning about an entry to Warn
+func (r *Reader) emit(kind string, e Entry, format string, a ...any) {
+	r.mu.Lock()
+	r.warn(kind, e.File, e.Name, format, a...)
+	r.unlock()
+}
+
+// the type of a file that is not regular, for warnings
+func fileType(mode fs.FileMode) string {
+	switch {
+	case mode&fs.ModeSymlink != 0:
+		return "a symbolic link"
+	case mode&fs.ModeNamedPipe != 0:
+		return "a FIFO"
+	case mode&fs.ModeSocket != 0:
+		return "a socket"
+	case mode&fs.ModeDevice != 0:
+		return "a device"
+	}
+	return "a special file"
+}
+
+// DirFS returns a ChtimesFS writing into the directory dir, which is made if missing.
+// Nothing is written through a symbolic link under dir, and an existing name that is not
+// a regular file is not replaced.
+func DirFS(dir string) ChtimesFS {
+	return dirFS(dir)
+}
+
+type dirFS string
+
+// get the path of a name in the directory
+func (dir dirFS) join(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return filepath.Join(string(dir), filepath.FromSlash(name)), nil
+}
+
+func (dir dirFS) MkdirAll(name string, perm fs.FileMode) error {
+	if _, err := dir.join("mkdir", name); err != nil {
+		return err
+	}
+	return mkdirBeneath(string(dir), name, perm)
+}
+
+func (dir dirFS) Create(name string, perm fs.FileMode) (io.WriteCloser, error) {
+	outpath, err := dir.join("create", name)
+	if err != nil {
+		return nil, err
+	}
+	// check the parents again, as Create may be called without MkdirAll
+	err = mkdirBeneath(string(dir), path.Dir(name), 0777)
+	if err != nil {
+		return nil, err
+	}
+	if st, err := os.Lstat(outpath); err == nil && !st.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s exists and is not a regular file", outpath)
+	}
+	return os.OpenFile(outpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+}
+
+func (dir dirFS) Chtimes(name string, atime, mtime time.Time) error {
+	outpath, err := dir.join("chtimes", name)
+	if err != nil {
+		return err
+	}
+	return os.Chtimes(outpath, atime, mtime)
+}
+
+// make the directories of a slash-separated relative path under dir,
+// refusing to go through a symbolic link or anything else that is not a directory
+func mkdirBeneath(dir, rel string, perm fs.FileMode) error {
+	if rel == "." {
+		return os.MkdirAll(dir, perm)
+	}
+	p := dir
+	for _, c := range strings.Split(rel, "/") {
+		p = filepath.Join(p, c)
+		st, err := os.Lstat(p)
+		if os.IsNotExist(err) {
+			err = os.MkdirAll(p, perm)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !st.IsDir() {
+			return fmt.Errorf("%s is not a directory", p)
+		}
+	}
+	return nil
+}
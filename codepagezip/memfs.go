@@ -0,0 +1,73 @@
+package codepagezip
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// A MemFS is a WriteFS in memory, for testing code that extracts archives.
+// It maps the names of files to their contents, and the names of directories,
+// with a slash at the end as in archives, to nil. It is not safe for concurrent use.
+type MemFS map[string][]byte
+
+func (m MemFS) MkdirAll(name string, perm fs.FileMode) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrInvalid}
+	}
+	for ; name != "."; name = path.Dir(name) {
+		if _, ok := m[name]; ok {
+			return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+		}
+		m[name+"/"] = nil
+	}
+	return nil
+}
+
+func (m MemFS) Create(name string, perm fs.FileMode) (io.WriteCloser, error) {
+	if !fs.ValidPath(name) || name == "." {
+		return nil, &fs.PathError{Op: "create", Path: name, Err: fs.ErrInvalid}
+	}
+	if _, ok := m[name+"/"]; ok {
+		return nil, &fs.PathError{Op: "create", Path: name, Err: fs.ErrExist}
+	}
+	return &memFile{m: m, name: name}, nil
+}
+
+// a file of a MemFS, stored when closed
+type memFile struct {
+	bytes.Buffer
+	m    MemFS
+	name string
+}
+
+func (f *memFile) Close() error {
+	f.m[f.name] = f.Bytes()
+	return nil
+}
+
+// Diff compares the tree in m with an expected one, where names ending with a slash are directories,
+// and returns the differences, one line for each name, sorted; none if the trees are the same.
+func (m MemFS) Diff(want map[string]string) []string {
+	var diff []string
+	for name, content := range want {
+		got, ok := m[name]
+		switch {
+		case !ok:
+			diff = append(diff, fmt.Sprintf("%s: missing", name))
+		case !strings.HasSuffix(name, "/") && string(got) != content:
+			diff = append(diff, fmt.Sprintf("%s: %q, want %q", name, got, content))
+		}
+	}
+	for name := range m {
+		if _, ok := want[name]; !ok {
+			diff = append(diff, fmt.Sprintf("%s: unexpected", name))
+		}
+	}
+	sort.Strings(diff)
+	return diff
+}
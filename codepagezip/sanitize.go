@@ -0,0 +1,48 @@
+package codepagezip
+
+import "strings"
+
+// SanitizeComponent makes a single path component name an entry inside the parent directory.
+func SanitizeComponent(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '/' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, s)
+	if s == "" || s == "." || s == ".." {
+		return "_"
+	}
+	return s
+}
+
+// SanitizePath makes an entry name into a relative slash-separated path.
+// Absolute prefixes, empty, "." and ".." components are dropped so the path never escapes the output directory.
+func SanitizePath(name string) string {
+	comp := strings.FieldsFunc(name, func(r rune) bool { return r == '/' || r == '\\' })
+	out := make([]string, 0, len(comp))
+	for _, c := range comp {
+		if c == "." || c == ".." {
+			continue
+		}
+		if len(out) == 0 && len(c) == 2 && c[1] == ':' { // drive letter
+			continue
+		}
+		out = append(out, SanitizeComponent(c))
+	}
+	return strings.Join(out, "/")
+}
+
+// SuspiciousName reports whether an entry name tries to leave the output directory or to be absolute,
+// which SanitizePath prevents.
+func SuspiciousName(name string) bool {
+	if strings.HasPrefix(name, "/") || strings.HasPrefix(name, "\\") || len(name) >= 2 && name[1] == ':' {
+		return true
+	}
+	for _, c := range strings.FieldsFunc(name, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if c == ".." {
+			return true
+		}
+	}
+	return false
+}
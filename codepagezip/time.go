@@ -0,0 +1,96 @@
+package codepagezip
+
+import (
+	"archive/zip"
+	"time"
+)
+
+// extra fields holding modification times in UTC, which archive/zip prefers to the DOS timestamp
+const (
+	extraNTFS       = 0x000a // NTFS
+	extraExtTime    = 0x5455 // extended timestamp
+	extraInfoZIPOld = 0x5855 // Info-ZIP Unix, old
+)
+
+// the Info-ZIP Unicode path extra field, which holds a name in UTF-8 with the CRC-32 of the raw name
+const extraUnicodePath = 0x7075
+
+// check if the extra data has a modification time in UTC
+func hasUTCTime(extra []byte) bool {
+	for len(extra) >= 4 {
+		tag := uint16(extra[0]) | uint16(extra[1])<<8
+		size := int(extra[2]) | int(extra[3])<<8
+		if 4+size > len(extra) {
+			break
+		}
+		switch tag {
+		case extraNTFS, extraExtTime, extraInfoZIPOld:
+			return true
+		}
+		extra = extra[4+size:]
+	}
+	return false
+}
+
+// remove the extra fields of a tag from the extra data
+func stripExtra(extra []byte, tag uint16) []byte {
+	var out []byte
+	for len(extra) >= 4 {
+		t := uint16(extra[0]) | uint16(extra[1])<<8
+		size := int(extra[2]) | int(extra[3])<<8
+		if 4+size > len(extra) {
+			break
+		}
+		if t != tag {
+			out = append(out, extra[:4+size]...)
+		}
+		extra = extra[4+size:]
+	}
+	return out
+}
+
+// HasModTime reports whether an entry records a modification time.
+// Some tools write a zero DOS date, which is not a valid date, when they have no time to record.
+func HasModTime(f *zip.File) bool {
+	return f.ModifiedDate != 0 || hasUTCTime(f.Extra)
+}
+
+// ModTime returns the modification time of an entry.
+// A DOS timestamp is a local time without a zone; archive/zip reads it as UTC, and it is
+// taken to be in loc instead, or in the local zone if loc is nil. Times in UTC are used as they are.
+func ModTime(f *zip.File, loc *time.Location) time.Time {
+	t := f.Modified
+	if hasUTCTime(f.Extra) {
+		return t
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+}
+
+// the earliest time a zip entry can record
+var dosEpoch = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// SkewedTime reports whether a modification time is implausible: in the future, or before the DOS epoch.
+func SkewedTime(t time.Time) bool {
+	return t.After(time.Now().Add(24*time.Hour)) || t.Before(dosEpoch.Add(-24*time.Hour))
+}
+
+// the precision of DOS timestamps, and of modification times on FAT filesystems
+const dosPrecision = 2 * time.Second
+
+// IsNewer reports whether the modification time of f, with DOS timestamps in loc, is later than t.
+// Differences within the 2-second precision of DOS timestamps do not count. Neither does a difference
+// of one hour for a DOS timestamp, which has no zone and may be shifted by daylight saving time,
+// by the system that made the archive or by the filesystem t comes from.
+func IsNewer(f *zip.File, loc *time.Location, t time.Time) bool {
+	d := ModTime(f, loc).Sub(t)
+	if d <= dosPrecision {
+		return false
+	}
+	if !hasUTCTime(f.Extra) && d >= time.Hour-dosPrecision && d <= time.Hour+dosPrecision {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,39 @@
+package codepagezip
+
+import (
+	"archive/zip"
+	"io"
+	"path"
+)
+
+// A Transform works on an entry on its way from a Reader into a WriteFS, after NameHook.
+// It gets the name and the content of the entry, nil for a directory, and returns the name to write
+// the entry under, or "" to leave it out, and the content to write, which it may wrap.
+// Transforms are applied in order, so ExtractTo(dst, t1, t2) wires the archive through t1 and t2 into dst.
+type Transform func(f *zip.File, name string, content io.Reader) (newName string, newContent io.Reader, err error)
+
+// Filter returns a Transform leaving out the entries whose names keep returns false for.
+func Filter(keep func(name string) bool) Transform {
+	return func(f *zip.File, name string, content io.Reader) (string, io.Reader, error) {
+		if !keep(name) {
+			return "", nil, nil
+		}
+		return name, content, nil
+	}
+}
+
+// TranscodeContent returns a Transform converting the contents of the files whose base names
+// match pattern, as path.Match takes it, from one codepage to another; "*.txt" for text files.
+func TranscodeContent(pattern, from, to string) Transform {
+	return func(f *zip.File, name string, content io.Reader) (string, io.Reader, error) {
+		if content == nil {
+			return name, nil, nil
+		}
+		ok, err := path.Match(pattern, path.Base(name))
+		if err != nil || !ok {
+			return name, content, err
+		}
+		content, err = NewConvertingReader(content, from, to)
+		return name, content, err
+	}
+}
@@ -0,0 +1,43 @@
+package codepagezip
+
+import (
+	"archive/zip"
+	"fmt"
+)
+
+// Kinds of warnings, as the command line tool counts them.
+const (
+	WarnName      = "name"      // a name leaving the output directory or absolute, which SanitizePath changes
+	WarnLossy     = "lossy"     // a name not valid in its codepage, converted with replacement characters
+	WarnSkipped   = "skipped"   // an entry ExtractTo does not extract
+	WarnTimestamp = "timestamp" // an implausible modification time
+)
+
+// A Warning is a problem with an entry that does not stop reading or extracting it.
+type Warning struct {
+	Kind    string // one of the Warn constants
+	File    *zip.File
+	Name    string // the converted name
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: %s", w.Name, w.Message)
+}
+
+// record a warning, to be given to Warn when r.mu is released; r.mu must be held
+func (r *Reader) warn(kind string, f *zip.File, name, format string, a ...any) {
+	if r.Warn != nil {
+		r.pending = append(r.pending, Warning{kind, f, name, fmt.Sprintf(format, a...)})
+	}
+}
+
+// give the recorded warnings to Warn, after releasing r.mu, so that Warn may call the methods of r
+func (r *Reader) unlock() {
+	pending := r.pending
+	r.pending = nil
+	r.mu.Unlock()
+	for _, w := range pending {
+		r.Warn(w)
+	}
+}
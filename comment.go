@@ -0,0 +1,159 @@
+package main
+
+import (
+	"archive/zip"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mixcode/codepage-unzip/codepagezip"
+)
+
+var (
+	setComment        = ""    // new comment for the comment command
+	transcodeComments = false // convert comments from -f to -t in the archive
+)
+
+// check if an encoding name is UTF-8
+func isUTF8(encoding string) bool {
+	return strings.EqualFold(strings.ReplaceAll(encoding, "-", ""), "utf8")
+}
+
+// view, set or transcode the archive comment and entry comments.
+//
+//	comment ZIPfile                             show the comments
+//	comment ZIPfile [entry] -set-comment text   set the comment of the archive or an entry
+//	comment ZIPfile -transcode-comments         convert all comments from -f to -t
+func runComment(args []string) (err error) {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("usage: comment ZIPfile [entry] [-set-comment text | -transcode-comments]")
+	}
+	zipname := args[0]
+	entryName := ""
+	if len(args) == 2 {
+		entryName = args[1]
+	}
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "set-comment" {
+			set = true
+		}
+	})
+
+	if !set && !transcodeComments {
+		return showComments(zipname, entryName)
+	}
+	if set && transcodeComments {
+		return fmt.Errorf("-set-comment and -transcode-comments cannot be used together")
+	}
+	if transcodeComments && entryName != "" {
+		return fmt.Errorf("-transcode-comments applies to the whole archive")
+	}
+	if _, err = os.Stat(zipname); err != nil {
+		return
+	}
+
+	found := false
+	return rewriteZip(zipname, func(zr *zip.Reader, zw *zip.Writer) error {
+		if set && entryName == "" {
+			// the archive comment has no encoding flag; store it in the codepage of the archive
+			c, err := codepagezip.ConvertString(setComment, convertTo, convertFrom)
+			if err != nil {
+				return fmt.Errorf("converting from %s to %s: %w", convertTo, convertFrom, err)
+			}
+			err = zw.SetComment(c)
+			if err != nil {
+				return err
+			}
+		}
+		if transcodeComments {
+			c, err := codepagezip.ConvertString(zr.Comment, convertFrom, convertTo)
+			if err != nil {
+				return fmt.Errorf("converting from %s to %s: %w", convertFrom, convertTo, err)
+			}
+			err = zw.SetComment(c)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, f := range zr.File {
+			name, err := convertName(f)
+			if err != nil {
+				return err
+			}
+			fh := f.FileHeader
+			switch {
+			case set && entryName != "" && name == entryName:
+				// the entry comment shares the encoding flag with the name
+				fh.Comment, err = codepagezip.ConvertString(setComment, convertTo, nameEncoding(f))
+				if err != nil {
+					return fmt.Errorf("converting from %s to %s: %w", convertTo, nameEncoding(f), err)
+				}
+				fh.Extra = stripExtra(fh.Extra, extraUnicodeComment) // it holds the old comment
+				found = true
+
+			case transcodeComments && f.NonUTF8 && f.Comment != "":
+				// the name and the comment share the encoding flag, so both are converted
+				fh.Comment, err = codepagezip.ConvertString(f.Comment, convertFrom, convertTo)
+				if err != nil {
+					return fmt.Errorf("converting from %s to %s: %w", convertFrom, convertTo, err)
+				}
+				fh.Name = name
+				// the Unicode fields hold the old name and comment, checked against the old bytes
+				fh.Extra = stripExtra(stripExtra(fh.Extra, extraUnicodePath), extraUnicodeComment)
+				if isUTF8(convertTo) {
+					fh.NonUTF8 = false
+					fh.Flags |= FLAG_EFS
+				}
+			}
+			err = copyZipEntryHeader(zw, f, &fh)
+			if err != nil {
+				return err
+			}
+		}
+		if set && entryName != "" && !found {
+			return fmt.Errorf("no entry named %s", entryName)
+		}
+		return nil
+	})
+}
+
+// print the archive comment and entry comments
+func showComments(zipname, entryName string) (err error) {
+	zr, err := zip.OpenReader(zipname)
+	if err != nil {
+		return
+	}
+	defer zr.Close()
+	if wantsDetection() {
+		defer applyDetection(zr.File)()
+	}
+
+	if entryName == "" && zr.Comment != "" {
+		c, err := codepagezip.ConvertString(zr.Comment, convertFrom, convertTo)
+		if err != nil {
+			return fmt.Errorf("converting from %s to %s: %w", convertFrom, convertTo, err)
+		}
+		fmt.Printf("%s\n", c)
+	}
+	for _, f := range zr.File {
+		if f.Comment == "" {
+			continue
+		}
+		name, err := convertName(f)
+		if err != nil {
+			return err
+		}
+		if entryName != "" && name != entryName {
+			continue
+		}
+		c, err := codepagezip.ConvertString(f.Comment, nameEncoding(f), convertTo)
+		if err != nil {
+			return fmt.Errorf("converting from %s to %s: %w", nameEncoding(f), convertTo, err)
+		}
+		fmt.Printf("%s: %s\n", name, c)
+	}
+	return
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	zeroCopy = false  // copy stored entries without reading them into the tool
+	zipFile  *os.File // the archive file, for zeroCopy
+)
+
+// error for -keep-going runs where some entries failed
+type failedEntriesError struct {
+	count int
+}
+
+func (e *failedEntriesError) Error() string {
+	return fmt.Sprintf(tr("%d entries could not be extracted"), e.count)
+}
+
+// a reader that counts bytes read, for watching the progress from another goroutine
+type progressReader struct {
+	r io.Reader
+	n atomic.Int64
+}
+
+func (p *progressReader) Read(b []byte) (n int, err error) {
+	n, err = p.r.Read(b)
+	p.n.Add(int64(n))
+	return
+}
+
+// copy the content of an entry.
+// The data must not be longer than the declared size, and reading must not stall longer than entryTimeout.
+func copyEntry(w io.Writer, r io.Reader, size uint64) (int64, error) {
+	copyData := func(r io.Reader) (int64, error) {
+		n, err := io.Copy(w, io.LimitReader(r, int64(size)+1))
+		if err == nil && n > int64(size) {
+			err = fmt.Errorf("the entry data is longer than its declared size; the stream may be corrupted")
+		}
+		return n, err
+	}
+	if entryTimeout <= 0 {
+		return copyData(r)
+	}
+
+	type result struct {
+		n   int64
+		err error
+	}
+	pr := &progressReader{r: r}
+	done := make(chan result, 1)
+	go func() {
+		defer handleCrash()
+		n, err := copyData(pr)
+		done <- result{n, err}
+	}()
+
+	tick := time.NewTicker(entryTimeout)
+	defer tick.Stop()
+	last := int64(-1)
+	for {
+		select {
+		case res := <-done:
+			return res.n, res.err
+		case <-tick.C:
+			n := pr.n.Load()
+			if n == last {
+				// the copying goroutine ends when the caller closes the reader and the writer
+				return n, fmt.Errorf("reading the entry stalled for %v", entryTimeout)
+			}
+			last = n
+		}
+	}
+}
+
+// copy a stored (uncompressed) entry straight from the archive file.
+// With *os.File on both sides the kernel copies the data (copy_file_range or sendfile) where available.
+// The CRC is not verified because the data does not pass through the tool.
+func copyStored(w io.Writer, entry *zip.File) (int64, error) {
+	off, err := entry.DataOffset()
+	if err != nil {
+		return 0, err
+	}
+	_, err = zipFile.Seek(off, io.SeekStart)
+	if err != nil {
+		return 0, err
+	}
+	return io.Copy(w, io.LimitReader(zipFile, int64(entry.CompressedSize64)))
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+)
+
+// the archive being processed, for the diagnostic report on a crash
+var crashArchive string
+
+// recover from a panic, and write a diagnostic report for a bug report.
+// Must be deferred directly by main and first by every goroutine the tool starts,
+// as a panic in a goroutine is not recovered by main.
+func handleCrash() {
+	p := recover()
+	if p == nil {
+		return
+	}
+	stack := debug.Stack()
+	fmt.Fprintf(os.Stderr, "panic: %v\n\n%s\n", p, stack)
+	name, err := writeCrashReport(p, stack)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not write a diagnostic report: %v\n", err)
+	} else {
+		fmt.Fprintf(os.Stderr, "A diagnostic report was written to %s.\n", name)
+		fmt.Fprintf(os.Stderr, "Please attach it to a bug report; note that it contains the names of the files in the archive.\n")
+	}
+	os.Exit(2)
+}
+
+// write the options, a dump of the central directory of the archive, and
+// the stack trace into a new file in the temporary directory.
+func writeCrashReport(p any, stack []byte) (name string, err error) {
+	f, err := os.CreateTemp("", filepath.Base(os.Args[0])+"-crash-*.txt")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if e := f.Close(); err == nil {
+			err = e
+		}
+	}()
+	name = f.Name()
+
+	w := &bytes.Buffer{}
+	fmt.Fprintf(w, "panic: %v\n\n", p)
+	printVersion(w, filepath.Base(os.Args[0]))
+
+	fmt.Fprintf(w, "\narguments:\n")
+	for _, a := range os.Args[1:] {
+		fmt.Fprintf(w, "  %q\n", a)
+	}
+	fmt.Fprintf(w, "options:\n")
+	flag.VisitAll(func(fl *flag.Flag) {
+		fmt.Fprintf(w, "  -%s=%q\n", fl.Name, fl.Value.String())
+	})
+
+	fmt.Fprintf(w, "\narchive: %q\n", crashArchive)
+	if crashArchive != "" {
+		dumpCentralDirectory(w, crashArchive)
+	}
+
+	fmt.Fprintf(w, "\nstack:\n%s", stack)
+	_, err = f.Write(w.Bytes())
+	return
+}
+
+// print the headers of the entries of a zip file, with their raw names in hex.
+func dumpCentralDirectory(w *bytes.Buffer, zipname string) {
+	zr, err := zip.OpenReader(zipname)
+	if err != nil {
+		fmt.Fprintf(w, "  cannot read the central directory: %v\n", err)
+		return
+	}
+	defer zr.Close()
+	fmt.Fprintf(w, "  %d entries, comment %q\n", len(zr.File), zr.Comment)
+	for i, entry := range zr.File {
+		offset, err := entry.DataOffset()
+		if err != nil {
+			offset = -1
+		}
+		fmt.Fprintf(w, "  #%d: method %d, flags %#04x, creator %#04x, compressed %d, size %d, crc %08x, data at %d, extra %d bytes\n",
+			i, entry.Method, entry.Flags, entry.CreatorVersion, entry.CompressedSize64, entry.UncompressedSize64, entry.CRC32, offset, len(entry.Extra))
+		fmt.Fprintf(w, "      name %s %q\n", hex.EncodeToString([]byte(entry.Name)), entry.Name)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"archive/zip"
+	"io"
+	"runtime"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// compression methods not supported by archive/zip
+const (
+	MethodZstd = 93
+)
+
+var decodeThreads = 0 // threads for decoding an entry; 0 for the number of CPUs
+
+type errReadCloser struct {
+	err error
+}
+
+func (e errReadCloser) Read([]byte) (int, error) { return 0, e.err }
+func (e errReadCloser) Close() error             { return nil }
+
+// register decompressors for additional compression methods.
+// Zstandard entries are decoded by multiple goroutines, so a single huge entry is not bound to one core.
+func registerDecompressors(zr *zip.Reader) {
+	threads := decodeThreads
+	if threads <= 0 {
+		threads = runtime.NumCPU()
+	}
+	zr.RegisterDecompressor(MethodZstd, func(r io.Reader) io.ReadCloser {
+		d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(threads))
+		if err != nil {
+			return errReadCloser{err}
+		}
+		return d.IOReadCloser()
+	})
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"archive/zip"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/mixcode/codepage-unzip/codepagezip"
+)
+
+// the -f value that detects the codepage from the names in the archive
+const EncodingAuto = "auto"
+
+// codepages tried by -f auto, in the order of preference on a tie
+var detectCandidates = []string{
+	"CP932", "EUC-JP", "CP949", "GBK", "BIG5", "CP1251", "CP866", "KOI8-R", "CP437", "CP1252",
+}
+
+// common characters in names; a random misdecoding rarely yields many of them
+const commonHan = "的一是不了人我在有他这中大来上国个到说们为子和你地出道也时年得就那要下以生会自着去之过家学对可她里后小么心多天而能好都然没日于起还发成事只作当想看文无开手十用主行方又如前所本见经头面公同三已老从动两长知民样现分将外但身些与高意进把法此实回二理美点月明其种声全工己话儿者向情部正名定女问力机给等几很业最间新什打便位因重被走电四第门相次东政海口使教西再平真听世气信北少关并内加化由却代军产入先山五太水万市眼体别处总才场师书比住员九笑性通目华报立马命张活难神数件安表原车白应路期叫死常提感金何更反合放做系计或司利受光王果亲界及今京务制解各任至清物台象记边共风战干接它许八特觉望直服毛林题建南度统色字请交爱让认算论百吃义科怎元社术结六功指思非流每青管夫连远资队跟带花快条院变联言权往展该领传近留红治决周保达办运武半候七必城父强步完革深区即求品士转量空众技轻程告江语英基派满式李息写识极令黑断线東書車門長話間電寫眞画像動資料規語韓國音楽樂集録錄版圖図檔案類別會說們個來對時過還國發後麼開經頭動兩長現將與進實點種聲話兒體員記邊風戰許覺務製實"
+
+const commonHangul = "이다의는에하고을를가지한기서로사으도리자들아인어대구시나수전정일그내상부해주보만게있것없적요방간면성제문모여라화소관과장원공중비위무신미연우마작진경파실저"
+
+var commonRunes = func() map[rune]bool {
+	m := make(map[rune]bool)
+	for _, r := range commonHan + commonHangul {
+		m[r] = true
+	}
+	return m
+}()
+
+// how plausible a decoded name is, by the characters it contains
+func nameScore(s string) float64 {
+	score := 0.0
+	var prev rune
+	for _, r := range s {
+		switch {
+		case r < 0x80:
+			// ASCII is the same in all candidates
+		case 0x3041 <= r && r <= 0x30ff: // hiragana and katakana
+			score += 3
+		case 0xac00 <= r && r <= 0xd7a3: // hangul syllables
+			if commonRunes[r] {
+				score += 3
+			} else {
+				score += 1
+			}
+		case unicode.Is(unicode.Han, r):
+			if commonRunes[r] {
+				score += 3
+			} else {
+				score += 0.5
+			}
+		case unicode.Is(unicode.Cyrillic, r):
+			switch {
+			case unicode.IsLower(r):
+				score += 1
+			case unicode.IsLower(prev):
+				score -= 1 // upper case in the middle of a word
+			case unicode.IsUpper(prev):
+				score += 0.5
+			default:
+				score += 1
+			}
+		case 0x3000 <= r && r <= 0x303f, 0xff01 <= r && r <= 0xff5e: // CJK symbols and full-width forms
+			score += 1.5
+		case 0xff61 <= r && r <= 0xff9f: // half-width katakana
+		case 0xc0 <= r && r <= 0xff && r != 0xd7 && r != 0xf7: // accented Latin letters
+			score += 0.5
+		case 0xa0 <= r && r <= 0xbf, 0x2000 <= r && r <= 0x206f:
+			score -= 0.5
+		default: // controls, box drawing, private use, replacement characters and other symbols
+			score -= 2
+		}
+		prev = r
+	}
+	return score
+}
+
+// the result of detecting the codepage of the names
+type detection struct {
+	encoding   string
+	confidence float64 // 0 to 1
+	runnerUp   string
+	samples    int // names that need a codepage
+}
+
+// guess the codepage of the names of the entries that are not UTF-8
+func detectEncoding(files []*zip.File) detection {
+	var names []string
+	for _, f := range files {
+		if f.NonUTF8 {
+			names = append(names, f.Name)
+		}
+	}
+	return detectNames(names)
+}
+
+// guess the codepage of raw names.
+// Each candidate is scored by the characters it decodes the names into,
+// per byte so that single- and double-byte codepages compare fairly.
+func detectNames(raw []string) detection {
+	var names []string
+	nbytes := 0
+	for _, name := range raw {
+		if !isASCII(name) {
+			names = append(names, name)
+			for i := 0; i < len(name); i++ {
+				if name[i] >= 0x80 {
+					nbytes++
+				}
+			}
+		}
+	}
+	if len(names) == 0 {
+		return detection{encoding: UTF8, confidence: 1}
+	}
+
+	best, second := -1, -1
+	scores := make([]float64, len(detectCandidates))
+	for i, enc := range detectCandidates {
+		c, err := codepagezip.NewConverter(enc, UTF8)
+		if err != nil {
+			scores[i] = -100
+			continue
+		}
+		score := 0.0
+		for _, name := range names {
+			s, err := c.ConvertString(name)
+			if err != nil {
+				score -= 4 * float64(len(name)) // not a valid name in this codepage
+				continue
+			}
+			score += nameScore(s)
+		}
+		c.Close()
+		scores[i] = score / float64(nbytes)
+
+		if best < 0 || scores[i] > scores[best] {
+			best, second = i, best
+		} else if second < 0 || scores[i] > scores[second] {
+			second = i
+		}
+	}
+
+	d := detection{encoding: detectCandidates[best], samples: len(names)}
+	if second >= 0 {
+		d.runnerUp = detectCandidates[second]
+	}
+	switch {
+	case scores[best] <= 0:
+		d.confidence = 0
+	case second < 0 || scores[second] <= 0:
+		d.confidence = 1
+	default:
+		d.confidence = 1 - scores[second]/scores[best]
+	}
+	return d
+}
+
+// replace -f auto by the detected codepage of the archive, and report it.
+// Returns a function restoring -f auto for the next archive.
+func applyDetection(files []*zip.File) (restore func()) {
+	return useDetection(detectEncoding(files))
+}
+
+// replace -f auto by a detected codepage, and report it
+func useDetection(d detection) (restore func()) {
+	convertFrom = d.encoding
+	autoDetected = true
+	if !quiet {
+		if d.samples == 0 {
+			fmt.Fprintf(os.Stderr, "All names are ASCII or UTF-8; no codepage is needed\n")
+		} else {
+			fmt.Fprintf(os.Stderr, "Detected codepage %s from %d names (confidence %.0f%%", d.encoding, d.samples, 100*d.confidence)
+			if d.runnerUp != "" {
+				fmt.Fprintf(os.Stderr, "; next best %s", d.runnerUp)
+			}
+			fmt.Fprintf(os.Stderr, ")\n")
+			if d.confidence < 0.2 {
+				fmt.Fprintf(os.Stderr, "The detection is uncertain; check the names with -l, and give -f if they are wrong\n")
+			}
+		}
+	}
+	return func() {
+		convertFrom = EncodingAuto
+		autoDetected = false
+	}
+}
+
+// whether -f asks for detection
+func wantsDetection() bool {
+	return strings.EqualFold(convertFrom, EncodingAuto)
+}
+
+// check if the codepage of the names is given with -f, or by a preset
+func codepageGiven() bool {
+	return flagGiven("f")
+}
+
+// check if a flag is given on the command line, or set by a preset
+func flagGiven(name string) bool {
+	given := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			given = true
+		}
+	})
+	return given
+}
+
+// -f auto has been replaced by a detected codepage
+var autoDetected = false
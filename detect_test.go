@@ -0,0 +1,62 @@
+package main
+
+import (
+	"archive/zip"
+	"testing"
+
+	"github.com/mixcode/codepage-unzip/codepagezip"
+)
+
+func TestDetectNames(t *testing.T) {
+	tests := []struct {
+		encoding string
+		names    []string
+	}{
+		{"CP932", []string{"テスト資料.txt", "新しいフォルダ/写真.jpg"}},
+		{"EUC-JP", []string{"テスト資料.txt", "新しいフォルダ/写真.jpg"}},
+		{"CP949", []string{"한국어 문서.hwp", "사진 모음/여름.jpg"}},
+		{"GBK", []string{"这是中国的文件.txt", "我的文档/照片.jpg"}},
+		{"BIG5", []string{"繁體中文資料.doc", "圖片檔案/照片.jpg"}},
+		{"CP1251", []string{"Документы/Отчет за год.doc", "Фотографии/лето.jpg"}},
+		{"CP866", []string{"Документы/Отчет за год.doc", "Фотографии/лето.jpg"}},
+		{"KOI8-R", []string{"Документы/Отчет за год.doc", "Фотографии/лето.jpg"}},
+	}
+	for _, tt := range tests {
+		var raw []string
+		for _, name := range tt.names {
+			r, err := codepagezip.ConvertString(name, UTF8, tt.encoding)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.encoding, err)
+			}
+			raw = append(raw, r)
+		}
+		d := detectNames(append(raw, "readme.txt"))
+		if d.encoding != tt.encoding || d.confidence <= 0 || d.samples != len(raw) {
+			t.Errorf("%s detected as %s (confidence %.2f, runner-up %s, %d samples)", tt.encoding, d.encoding, d.confidence, d.runnerUp, d.samples)
+		}
+	}
+
+	// ASCII names need no codepage
+	if d := detectNames([]string{"readme.txt", "src/main.go"}); d.encoding != UTF8 || d.confidence != 1 || d.samples != 0 {
+		t.Errorf("ASCII names detected as %+v", d)
+	}
+}
+
+// only the names without the UTF-8 flag are looked at
+func TestDetectEncoding(t *testing.T) {
+	sjis, err := codepagezip.ConvertString("テスト資料.txt", UTF8, "CP932")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := []*zip.File{
+		{FileHeader: zip.FileHeader{Name: sjis, NonUTF8: true}},
+		{FileHeader: zip.FileHeader{Name: "Документы/Отчет за год.doc"}},
+		{FileHeader: zip.FileHeader{Name: "Фотографии/лето.jpg"}},
+	}
+	if d := detectEncoding(files); d.encoding != "CP932" || d.samples != 1 {
+		t.Errorf("detected as %+v", d)
+	}
+	if d := detectEncoding(files[1:]); d.encoding != UTF8 {
+		t.Errorf("UTF-8 names detected as %+v", d)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mixcode/codepage-unzip/codepagezip"
+)
+
+const (
+	maxDiffSize  = 64 * 1024 // largest file to offer a diff for
+	diffContext  = 3         // lines of context around changes
+	maxDiffLines = 2000      // largest number of lines to compare
+)
+
+// report whether the data looks like text
+func isText(b []byte) bool {
+	return bytes.IndexByte(b, 0) < 0
+}
+
+// read the contents of an entry and an existing file for a diff.
+// ok is false if they are too large or not text.
+func diffSources(entry *zip.File, outpath string) (old, new []byte, ok bool) {
+	if entry.UncompressedSize64 > maxDiffSize {
+		return
+	}
+	st, err := os.Stat(outpath)
+	if err != nil || !st.Mode().IsRegular() || st.Size() > maxDiffSize {
+		return
+	}
+	old, err = os.ReadFile(outpath)
+	if err != nil || !isText(old) {
+		return
+	}
+	r, err := entry.Open()
+	if err != nil {
+		return
+	}
+	defer r.Close()
+	new, err = io.ReadAll(io.LimitReader(r, maxDiffSize+1))
+	if err != nil || len(new) > maxDiffSize || !isText(new) {
+		return
+	}
+	if !utf8.Valid(new) {
+		// the entry is in the archive codepage
+		s, err := codepagezip.ConvertString(string(new), convertFrom, convertTo)
+		if err == nil {
+			new = []byte(s)
+		}
+	}
+	return old, new, true
+}
+
+func splitLines(b []byte) []string {
+	if len(b) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(b), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+	a, b int // line indices in the old and the new text
+}
+
+// compute a line diff of a and b by the longest common subsequence
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var ops []diffOp
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i], i, j})
+			i++
+			j++
+		case i < n && (j == m || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', a[i], i, j})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j], i, j})
+			j++
+		}
+	}
+	return ops
+}
+
+// format a unified diff between old and new
+func unifiedDiff(old, new []byte, oldName, newName string) string {
+	a, b := splitLines(old), splitLines(new)
+	if len(a) > maxDiffLines || len(b) > maxDiffLines {
+		return "(too many lines to compare)\n"
+	}
+	ops := diffLines(a, b)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		// extend the hunk until a run of unchanged lines is long enough to split
+		start := max(i-diffContext, 0)
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			k := end
+			for k < len(ops) && ops[k].kind == ' ' {
+				k++
+			}
+			if k == len(ops) || k-end > 2*diffContext {
+				end = min(end+diffContext, len(ops))
+				break
+			}
+			end = k
+		}
+		var na, nb int
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				na++
+			}
+			if op.kind != '-' {
+				nb++
+			}
+		}
+		sa, sb0 := ops[start].a, ops[start].b
+		if na > 0 {
+			sa++
+		}
+		if nb > 0 {
+			sb0++
+		}
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", sa, na, sb0, nb)
+		for _, op := range ops[start:end] {
+			sb.WriteByte(op.kind)
+			sb.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = end
+	}
+	return sb.String()
+}
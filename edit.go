@@ -0,0 +1,377 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/mixcode/codepage-unzip/codepagezip"
+)
+
+// rewrite a zip file in place.
+// The edit function copies the entries it wants to keep from zr into zw, and adds new ones.
+// If the file does not exist, zr is nil and a new archive is made.
+func rewriteZip(filename string, edit func(zr *zip.Reader, zw *zip.Writer) error) (err error) {
+	var zr *zip.Reader
+	mode := fs.FileMode(0666)
+	if st, e := os.Stat(filename); e == nil {
+		rc, e := zip.OpenReader(filename)
+		if e != nil {
+			return e
+		}
+		defer rc.Close()
+		zr = &rc.Reader
+		mode = st.Mode().Perm()
+		if wantsDetection() {
+			defer applyDetection(zr.File)()
+		}
+	} else if !os.IsNotExist(e) {
+		return e
+	}
+
+	// write to a temporary file in the same directory, then replace the original
+	dir, file := filepath.Split(filename)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := os.CreateTemp(dir, "."+file+".*")
+	if err != nil {
+		return
+	}
+	tmpname := tmp.Name()
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmpname)
+		}
+	}()
+
+	zw := zip.NewWriter(tmp)
+	if zr != nil {
+		err = zw.SetComment(zr.Comment)
+		if err != nil {
+			return
+		}
+	}
+	err = edit(zr, zw)
+	if err != nil {
+		return
+	}
+	err = zw.Close()
+	if err != nil {
+		return
+	}
+	err = tmp.Close()
+	if err != nil {
+		return
+	}
+	err = os.Chmod(tmpname, mode)
+	if err != nil {
+		return
+	}
+	return os.Rename(tmpname, filename)
+}
+
+// copy an entry into another archive as it is
+func copyZipEntry(zw *zip.Writer, f *zip.File) error {
+	fh := f.FileHeader
+	return copyZipEntryHeader(zw, f, &fh)
+}
+
+// copy the data of an entry into another archive with a modified header
+func copyZipEntryHeader(zw *zip.Writer, f *zip.File, fh *zip.FileHeader) error {
+	if strings.HasSuffix(f.Name, "/") && f.UncompressedSize64 == 0 {
+		// a directory; some archivers compress its empty data, which zip.Writer refuses to copy
+		fh.Method = zip.Store
+		fh.CompressedSize, fh.CompressedSize64 = 0, 0
+		fh.CRC32 = 0
+		_, err := zw.CreateRaw(fh)
+		return err
+	}
+	w, err := zw.CreateRaw(fh)
+	if err != nil {
+		return err
+	}
+	r, err := f.OpenRaw()
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// check if a converted entry name matches a pattern, or is under a directory that matches
+func matchEntry(pattern, name string) bool {
+	_, ok := matchPrefix(pattern, name)
+	return ok
+}
+
+// find the longest leading part of a converted entry name that matches a pattern
+func matchPrefix(pattern, name string) (prefix string, ok bool) {
+	name = strings.TrimSuffix(name, "/")
+	for name != "." && name != "/" && name != "" {
+		if ok, _ := path.Match(pattern, name); ok {
+			return name, true
+		}
+		name = path.Dir(name)
+	}
+	return "", false
+}
+
+// remove an extra field of the given ID from the extra data
+func stripExtra(extra []byte, id uint16) []byte {
+	var out []byte
+	for len(extra) >= 4 {
+		tag := uint16(extra[0]) | uint16(extra[1])<<8
+		size := int(extra[2]) | int(extra[3])<<8
+		if 4+size > len(extra) {
+			break
+		}
+		if tag != id {
+			out = append(out, extra[:4+size]...)
+		}
+		extra = extra[4+size:]
+	}
+	return out
+}
+
+// add files to an archive; names are stored in UTF-8, relative to the parent directory of each argument.
+// An existing entry with the same name is replaced.
+func runAdd(args []string) (err error) {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: add ZIPfile files...")
+	}
+	zipname := args[0]
+	if encryptEntries && entryPassword == "" {
+		return fmt.Errorf("-encrypt needs -password")
+	}
+	if !encryptEntries && entryPassword != "" {
+		return fmt.Errorf("-password is for -encrypt")
+	}
+
+	// collect the files to add
+	type addFile struct {
+		path string // path on disk
+		name string // name in the archive
+		info fs.FileInfo
+	}
+	var files []addFile
+	added := make(map[string]bool)
+	for _, arg := range args[1:] {
+		// names are relative to the parent of the argument, as zip and 7-Zip store them:
+		// /home/u/f is stored as f, and src/a as src/a
+		parent := filepath.Dir(filepath.Clean(arg))
+		err = filepath.WalkDir(arg, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(parent, p)
+			if err != nil {
+				return err
+			}
+			name := codepagezip.SanitizePath(filepath.ToSlash(rel))
+			if name == "" {
+				return nil
+			}
+			if d.IsDir() {
+				name += "/"
+			} else if !info.Mode().IsRegular() {
+				return fmt.Errorf("%s is not a regular file", p)
+			}
+			if !added[name] {
+				files = append(files, addFile{p, name, info})
+				added[name] = true
+			}
+			return nil
+		})
+		if err != nil {
+			return
+		}
+	}
+
+	return rewriteZip(zipname, func(zr *zip.Reader, zw *zip.Writer) error {
+		if zr != nil {
+			for _, f := range zr.File {
+				name, err := convertName(f)
+				if err != nil {
+					return err
+				}
+				if added[name] {
+					continue // replaced
+				}
+				err = copyZipEntry(zw, f)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		for _, af := range files {
+			if !quiet {
+				fmt.Printf("adding: %s\n", af.name)
+			}
+			fh, err := zip.FileInfoHeader(af.info)
+			if err != nil {
+				return err
+			}
+			fh.Name = af.name // Go sets the EFS flag for non-ASCII UTF-8 names
+			if af.info.IsDir() {
+				_, err = zw.CreateHeader(fh)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			fi, err := os.Open(af.path)
+			if err != nil {
+				return err
+			}
+			if encryptEntries {
+				err = createEncrypted(zw, fh, fi, []byte(entryPassword), filepath.Dir(zipname))
+			} else {
+				fh.Method = zip.Deflate
+				var w io.Writer
+				w, err = zw.CreateHeader(fh)
+				if err == nil {
+					_, err = io.Copy(w, fi)
+				}
+			}
+			fi.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// delete entries matching patterns from an archive
+func runDelete(args []string) (err error) {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: delete ZIPfile patterns...")
+	}
+	zipname, patterns := args[0], args[1:]
+	for _, p := range patterns {
+		if _, err = path.Match(p, ""); err != nil {
+			return fmt.Errorf("pattern %s: %w", p, err)
+		}
+	}
+	if _, err = os.Stat(zipname); err != nil {
+		return
+	}
+
+	deleted := 0
+	err = rewriteZip(zipname, func(zr *zip.Reader, zw *zip.Writer) error {
+		for _, f := range zr.File {
+			name, err := convertName(f)
+			if err != nil {
+				return err
+			}
+			match := false
+			for _, p := range patterns {
+				if matchEntry(p, name) {
+					match = true
+					break
+				}
+			}
+			if match {
+				if !quiet {
+					fmt.Printf("deleting: %s\n", name)
+				}
+				deleted++
+				continue
+			}
+			err = copyZipEntry(zw, f)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil && deleted == 0 {
+		err = fmt.Errorf("no entries matched")
+	}
+	return
+}
+
+const (
+	extraUnicodePath    = 0x7075 // Info-ZIP Unicode Path extra field
+	extraUnicodeComment = 0x6375 // Info-ZIP Unicode Comment extra field
+)
+
+// rename entries of an archive without extracting.
+// Entries under a renamed directory are moved with it. New names are stored in UTF-8.
+func runRename(args []string) (err error) {
+	if len(args) != 3 {
+		return fmt.Errorf("usage: rename ZIPfile pattern newname")
+	}
+	zipname, pattern, newname := args[0], args[1], strings.Trim(args[2], "/")
+	if _, err = path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("pattern %s: %w", pattern, err)
+	}
+	if codepagezip.SanitizePath(newname) != newname {
+		return fmt.Errorf("invalid new name %s", newname)
+	}
+	if _, err = os.Stat(zipname); err != nil {
+		return
+	}
+
+	renamed := 0
+	err = rewriteZip(zipname, func(zr *zip.Reader, zw *zip.Writer) error {
+		// compute the new names first to find collisions
+		newNames := make([]string, len(zr.File))
+		seen := make(map[string]bool)
+		for i, f := range zr.File {
+			name, err := convertName(f)
+			if err != nil {
+				return err
+			}
+			if prefix, ok := matchPrefix(pattern, name); ok {
+				newNames[i] = newname + name[len(prefix):]
+				name = newNames[i]
+			}
+			if seen[name] {
+				return fmt.Errorf("renaming makes a duplicated name %s", name)
+			}
+			seen[name] = true
+		}
+
+		for i, f := range zr.File {
+			if newNames[i] == "" {
+				err := copyZipEntry(zw, f)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if !quiet {
+				name, _ := convertName(f)
+				fmt.Printf("renaming: %s -> %s\n", name, newNames[i])
+			}
+			renamed++
+
+			fh := f.FileHeader
+			fh.Name = newNames[i]
+			fh.NonUTF8 = false
+			fh.Flags |= FLAG_EFS                              // CreateRaw does not set it
+			fh.Extra = stripExtra(fh.Extra, extraUnicodePath) // it holds the old name
+			err := copyZipEntryHeader(zw, f, &fh)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil && renamed == 0 {
+		err = fmt.Errorf("no entries matched")
+	}
+	return
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// the names and contents of the entries of a ZIP file, with the names converted from -f
+func readZip(t *testing.T, filename string) map[string]string {
+	t.Helper()
+	zr, err := zip.OpenReader(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	entries := make(map[string]string)
+	for _, f := range zr.File {
+		name, err := convertName(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[name] = string(b)
+	}
+	return entries
+}
+
+// set the options of the edit commands for a test
+func editOptions(t *testing.T, codepage string) {
+	from, q := convertFrom, quiet
+	t.Cleanup(func() { convertFrom, quiet = from, q })
+	convertFrom, quiet = codepage, true
+}
+
+func TestAdd(t *testing.T) {
+	editOptions(t, "SHIFT-JIS")
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"src/a.txt":     "a",
+		"src/sub/b.txt": "b",
+		"日本語.txt":       "n",
+	} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(p), 0777)
+		if err := os.WriteFile(p, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	zipname := filepath.Join(dir, "t.zip")
+	writeZip(t, zipname, [][2]string{{"\x93\xfa\x96{\x8c\xea.txt", "old"}, {"keep.txt", "k"}})
+
+	tests := []struct {
+		args []string
+		want map[string]string
+	}{
+		// a directory is stored under its name, and a file given by its full path under its base name,
+		// replacing the entry of the same converted name
+		{[]string{filepath.Join(dir, "src") + string(filepath.Separator), filepath.Join(dir, "日本語.txt")}, map[string]string{
+			"keep.txt":      "k",
+			"src/":          "",
+			"src/a.txt":     "a",
+			"src/sub/":      "",
+			"src/sub/b.txt": "b",
+			"日本語.txt":       "n",
+		}},
+		{[]string{filepath.Join(dir, "src", "sub", "b.txt")}, map[string]string{
+			"keep.txt":      "k",
+			"src/":          "",
+			"src/a.txt":     "a",
+			"src/sub/":      "",
+			"src/sub/b.txt": "b",
+			"日本語.txt":       "n",
+			"b.txt":         "b",
+		}},
+	}
+	for _, tt := range tests {
+		if err := runAdd(append([]string{zipname}, tt.args...)); err != nil {
+			t.Fatal(err)
+		}
+		if got := readZip(t, zipname); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("add %q: %q, want %q", tt.args, got, tt.want)
+		}
+	}
+	if err := runAdd([]string{zipname, filepath.Join(dir, "none")}); err == nil {
+		t.Error("a missing file was added")
+	}
+}
+
+func TestDelete(t *testing.T) {
+	editOptions(t, "SHIFT-JIS")
+	zipname := filepath.Join(t.TempDir(), "t.zip")
+	writeZip(t, zipname, [][2]string{
+		{"\x83e\x83X\x83g/", ""},
+		{"\x83e\x83X\x83g/a.txt", "a"},
+		{"\x83e\x83X\x83g2.txt", "2"},
+		{"b.txt", "b"},
+		{"c.md", "c"},
+	})
+	tests := []struct {
+		patterns []string
+		want     map[string]string
+		fails    bool
+	}{
+		{[]string{"テスト"}, map[string]string{"テスト2.txt": "2", "b.txt": "b", "c.md": "c"}, false},
+		{[]string{"*.txt", "none"}, map[string]string{"c.md": "c"}, false},
+		{[]string{"none"}, map[string]string{"c.md": "c"}, true},
+		{[]string{"["}, map[string]string{"c.md": "c"}, true},
+	}
+	for _, tt := range tests {
+		err := runDelete(append([]string{zipname}, tt.patterns...))
+		if (err != nil) != tt.fails {
+			t.Errorf("delete %q: %v", tt.patterns, err)
+		}
+		if got := readZip(t, zipname); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("delete %q: %q, want %q", tt.patterns, got, tt.want)
+		}
+	}
+}
+
+func TestRename(t *testing.T) {
+	editOptions(t, "SHIFT-JIS")
+	zipname := filepath.Join(t.TempDir(), "t.zip")
+	writeZip(t, zipname, [][2]string{
+		{"\x83e\x83X\x83g/", ""},
+		{"\x83e\x83X\x83g/a.txt", "a"},
+		{"b.txt", "b"},
+		{"c.txt", "c"},
+	})
+	tests := []struct {
+		pattern, newname string
+		want             map[string]string
+		fails            bool
+	}{
+		{"テスト", "試験/", map[string]string{"試験/": "", "試験/a.txt": "a", "b.txt": "b", "c.txt": "c"}, false},
+		{"b.txt", "c.txt", map[string]string{"試験/": "", "試験/a.txt": "a", "b.txt": "b", "c.txt": "c"}, true},
+		{"b.txt", "../x.txt", map[string]string{"試験/": "", "試験/a.txt": "a", "b.txt": "b", "c.txt": "c"}, true},
+		{"none", "x", map[string]string{"試験/": "", "試験/a.txt": "a", "b.txt": "b", "c.txt": "c"}, true},
+		{"?.txt", "d", map[string]string{"試験/": "", "試験/a.txt": "a", "b.txt": "b", "c.txt": "c"}, true}, // both would become d
+		{"b.txt", "d.txt", map[string]string{"試験/": "", "試験/a.txt": "a", "d.txt": "b", "c.txt": "c"}, false},
+	}
+	for _, tt := range tests {
+		err := runRename([]string{zipname, tt.pattern, tt.newname})
+		if (err != nil) != tt.fails {
+			t.Errorf("rename %s %s: %v", tt.pattern, tt.newname, err)
+		}
+		if got := readZip(t, zipname); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("rename %s %s: %q, want %q", tt.pattern, tt.newname, got, tt.want)
+		}
+	}
+
+	zr, err := zip.OpenReader(zipname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if f.Name == "試験/a.txt" && (f.NonUTF8 || f.Flags&FLAG_EFS == 0) {
+			t.Errorf("%s: not flagged as UTF-8", f.Name)
+		}
+	}
+}
+
+// transcoding the comments drops the Unicode extra fields, which hold the old name and comment
+func TestTranscodeComments(t *testing.T) {
+	editOptions(t, "SHIFT-JIS")
+	defer func(to string, tc bool) { convertTo, transcodeComments = to, tc }(convertTo, transcodeComments)
+	convertTo, transcodeComments = "UTF-8", true
+
+	zipname := filepath.Join(t.TempDir(), "t.zip")
+	f, err := os.Create(zipname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	unicodeField := func(id uint16, s string) []byte {
+		b := []byte{byte(id), byte(id >> 8), byte(5 + len(s)), 0, 1, 0, 0, 0, 0}
+		return append(b, s...)
+	}
+	extra := append(unicodeField(extraUnicodePath, "old"), unicodeField(extraUnicodeComment, "old")...)
+	extra = append(extra, 0xfe, 0xca, 0, 0) // another field, kept
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: "\x83e\x83X\x83g.txt", Comment: "\x92\x8d\x8e\xdf", NonUTF8: true, Extra: extra})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("t"))
+	if err = zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	if err = runComment([]string{zipname}); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.OpenReader(zipname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	fh := zr.File[0].FileHeader
+	if fh.Name != "テスト.txt" || fh.Comment != "注釈" || fh.NonUTF8 {
+		t.Errorf("converted to %q, %q, NonUTF8 %v", fh.Name, fh.Comment, fh.NonUTF8)
+	}
+	if want := []byte{0xfe, 0xca, 0, 0}; string(stripExtra(fh.Extra, 0x5455)) != string(want) {
+		t.Errorf("extra fields % x, want % x", fh.Extra, want)
+	}
+}
+
+// delete and rename patterns match a name, or a directory above it
+func TestMatchPrefix(t *testing.T) {
+	tests := []struct {
+		pattern, name, want string
+		ok                  bool
+	}{
+		{"a.txt", "a.txt", "a.txt", true},
+		{"*.txt", "a.txt", "a.txt", true},
+		{"*.txt", "dir/a.txt", "", false},
+		{"dir", "dir/", "dir", true},
+		{"dir", "dir/sub/a.txt", "dir", true},
+		{"dir/*", "dir/sub/a.txt", "dir/sub", true},
+		{"d?r", "dir/a.txt", "dir", true},
+		{"dir", "dir2/a.txt", "", false},
+		{"sub", "dir/sub/a.txt", "", false},
+		{"[", "[", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := matchPrefix(tt.pattern, tt.name)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("matchPrefix(%q, %q) = %q, %v, want %q, %v", tt.pattern, tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
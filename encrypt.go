@@ -0,0 +1,177 @@
+package main
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"hash"
+	"io"
+	"os"
+	"time"
+)
+
+var (
+	encryptEntries = false // add: encrypt the new entries with WinZip AES-256
+	entryPassword  = ""    // add: the password of -encrypt
+)
+
+const (
+	aesStrength256 = 3 // the key strength in the extra field
+	aesVersionAE2  = 2 // AE-2: the CRC is not stored, as it would tell about short contents
+	aesKeyLen      = 32
+	aesSaltLen     = aesKeyLen / 2
+	aesMACLen      = 10 // the authentication code is the first 10 bytes of the HMAC-SHA1
+
+	zipVersionAES = 51 // the version needed to extract AES encrypted entries
+	extraTime     = 0x5455
+)
+
+// the counter mode of WinZip AES: a little-endian counter starting from 1,
+// unlike cipher.NewCTR which counts big-endian
+type winzipCTR struct {
+	block   cipher.Block
+	counter [aes.BlockSize]byte
+	key     [aes.BlockSize]byte
+	used    int
+}
+
+func newWinzipCTR(block cipher.Block) *winzipCTR {
+	return &winzipCTR{block: block, used: aes.BlockSize}
+}
+
+func (c *winzipCTR) XORKeyStream(dst, src []byte) {
+	for i := range src {
+		if c.used == aes.BlockSize {
+			for j := range c.counter {
+				c.counter[j]++
+				if c.counter[j] != 0 {
+					break
+				}
+			}
+			c.block.Encrypt(c.key[:], c.counter[:])
+			c.used = 0
+		}
+		dst[i] = src[i] ^ c.key[c.used]
+		c.used++
+	}
+}
+
+// encrypt and authenticate the data written through it
+type aesWriter struct {
+	w   io.Writer
+	ctr *winzipCTR
+	mac hash.Hash
+	buf []byte
+}
+
+func (a *aesWriter) Write(p []byte) (int, error) {
+	a.buf = append(a.buf[:0], p...)
+	a.ctr.XORKeyStream(a.buf, a.buf)
+	a.mac.Write(a.buf)
+	return a.w.Write(a.buf)
+}
+
+// the WinZip AES extra field, holding the key strength and the real compression method
+func aesExtra(method uint16) []byte {
+	b := make([]byte, 4+7)
+	binary.LittleEndian.PutUint16(b[0:], extraAES)
+	binary.LittleEndian.PutUint16(b[2:], 7)
+	binary.LittleEndian.PutUint16(b[4:], aesVersionAE2)
+	copy(b[6:], "AE")
+	b[8] = aesStrength256
+	binary.LittleEndian.PutUint16(b[9:], method)
+	return b
+}
+
+// set the DOS time and the extended timestamp, as zip.Writer.CreateHeader does but CreateRaw does not
+func setRawModTime(fh *zip.FileHeader, t time.Time) {
+	if t.Year() < 1980 {
+		t = time.Date(1980, 1, 1, 0, 0, 0, 0, t.Location())
+	}
+	fh.ModifiedDate = uint16(t.Day() + int(t.Month())<<5 + (t.Year()-1980)<<9)
+	fh.ModifiedTime = uint16(t.Second()/2 + t.Minute()<<5 + t.Hour()<<11)
+	b := make([]byte, 4+5)
+	binary.LittleEndian.PutUint16(b[0:], extraTime)
+	binary.LittleEndian.PutUint16(b[2:], 5)
+	b[4] = 1 // the modification time is present
+	binary.LittleEndian.PutUint32(b[5:], uint32(t.Unix()))
+	fh.Extra = append(fh.Extra, b...)
+}
+
+// add a deflated entry encrypted with WinZip AES-256 (AE-2).
+// The local header holds the sizes before the data, so the entry is made in a temporary file in dir first;
+// the directory of the archive, as rewriteZip uses, rather than a system temporary directory that may be small.
+func createEncrypted(zw *zip.Writer, fh *zip.FileHeader, r io.Reader, password []byte, dir string) (err error) {
+	tmp, err := os.CreateTemp(dir, ".codepage-unzip-*")
+	if err != nil {
+		return
+	}
+	defer func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}()
+
+	salt := make([]byte, aesSaltLen)
+	_, err = rand.Read(salt)
+	if err != nil {
+		return
+	}
+	keys := pbkdf2SHA1(password, salt, 1000, 2*aesKeyLen+2)
+	block, err := aes.NewCipher(keys[:aesKeyLen])
+	if err != nil {
+		return
+	}
+	mac := hmac.New(sha1.New, keys[aesKeyLen:2*aesKeyLen])
+	_, err = tmp.Write(append(salt, keys[2*aesKeyLen:]...)) // the salt and the password verification value
+	if err != nil {
+		return
+	}
+	fw, err := flate.NewWriter(&aesWriter{w: tmp, ctr: newWinzipCTR(block), mac: mac}, flate.DefaultCompression)
+	if err != nil {
+		return
+	}
+	n, err := io.Copy(fw, r)
+	if err != nil {
+		return
+	}
+	err = fw.Close()
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(mac.Sum(nil)[:aesMACLen])
+	if err != nil {
+		return
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return
+	}
+	_, err = tmp.Seek(0, io.SeekStart)
+	if err != nil {
+		return
+	}
+
+	fh.Method = MethodAES
+	fh.Flags |= FLAG_ENCRYPTED
+	if !isASCII(fh.Name) {
+		fh.Flags |= FLAG_EFS // CreateRaw does not set it
+	}
+	fh.CreatorVersion = fh.CreatorVersion&0xff00 | zipVersionAES
+	fh.ReaderVersion = zipVersionAES
+	fh.CRC32 = 0
+	fh.UncompressedSize64 = uint64(n)
+	fh.CompressedSize64 = uint64(size)
+	setRawModTime(fh, fh.Modified)
+	fh.Extra = append(fh.Extra, aesExtra(zip.Deflate)...)
+	w, err := zw.CreateRaw(fh)
+	if err != nil {
+		return
+	}
+	_, err = io.Copy(w, tmp)
+	return
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateEncrypted(t *testing.T) {
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	fh := &zip.FileHeader{Name: "日本語.txt", Modified: time.Date(2024, 5, 6, 7, 8, 10, 0, time.UTC)}
+	content := strings.Repeat("0123456789abcdef", 100)
+	if err := createEncrypted(zw, fh, strings.NewReader(content), []byte("secret"), t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := zr.File[0]
+	if f.Name != "日本語.txt" || f.Flags&FLAG_EFS == 0 || f.Method != MethodAES || f.UncompressedSize64 != uint64(len(content)) {
+		t.Errorf("header: name %q, flags %#x, method %d, size %d", f.Name, f.Flags, f.Method, f.UncompressedSize64)
+	}
+	if !f.Modified.Equal(fh.Modified) {
+		t.Errorf("modified %v, want %v", f.Modified, fh.Modified)
+	}
+	for _, tt := range []struct {
+		password string
+		want     bool
+	}{{"secret", true}, {"Secret", false}} {
+		ok, err := checkPassword(f, []byte(tt.password))
+		if err != nil || ok != tt.want {
+			t.Errorf("checkPassword(%q) = %v, %v; want %v", tt.password, ok, err, tt.want)
+		}
+	}
+}
+
+// the test vectors of RFC 6070
+func TestPBKDF2SHA1(t *testing.T) {
+	tests := []struct {
+		password, salt string
+		iter, keyLen   int
+		want           string
+	}{
+		{"password", "salt", 1, 20, "0c60c80f961f0e71f3a9b524af6012062fe037a6"},
+		{"password", "salt", 2, 20, "ea6c014dc72d6f8ccd1ed92ace1d41f0d8de8957"},
+		{"password", "salt", 4096, 20, "4b007901b765489abead49d926f721d065a429c1"},
+		{"passwordPASSWORDpassword", "saltSALTsaltSALTsaltSALTsaltSALTsalt", 4096, 25, "3d2eec4fe41c849b80c8d83662c0e44a8b291a964cf2f07038"},
+		{"pass\x00word", "sa\x00lt", 4096, 16, "56fa6aa75548099dcc37d7f03425e0c3"},
+	}
+	for _, tt := range tests {
+		if got := hex.EncodeToString(pbkdf2SHA1([]byte(tt.password), []byte(tt.salt), tt.iter, tt.keyLen)); got != tt.want {
+			t.Errorf("pbkdf2SHA1(%q, %q, %d, %d) = %s, want %s", tt.password, tt.salt, tt.iter, tt.keyLen, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var encodingStats = false // count the encodings used for archives in a local file
+
+// the local file counting the encodings used, and how each was chosen
+func encodingStatsFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "codepage-unzip", "encoding-stats.json"), nil
+}
+
+// how the codepage of the names was chosen: "detected" by -f auto, "given" by -f, or the "default"
+func encodingSource() string {
+	if autoDetected {
+		return "detected"
+	}
+	src := "default"
+	if codepageGiven() {
+		src = "given"
+	}
+	return src
+}
+
+// count the codepage used for the names of an archive, if any name needed one.
+// The file is never sent anywhere; it is only for the user to inspect or share.
+func recordEncoding(files []*zip.File) error {
+	needed := false
+	for _, f := range files {
+		if f.NonUTF8 && !isASCII(f.Name) {
+			needed = true
+			break
+		}
+	}
+	if !needed {
+		return nil
+	}
+
+	filename, err := encodingStatsFile()
+	if err != nil {
+		return err
+	}
+	counts := make(map[string]map[string]int) // encoding -> source -> count
+	data, err := os.ReadFile(filename)
+	if err == nil {
+		err = json.Unmarshal(data, &counts)
+		if err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	encoding := strings.ToLower(convertFrom)
+	if counts[encoding] == nil {
+		counts[encoding] = make(map[string]int)
+	}
+	counts[encoding][encodingSource()]++
+
+	data, err = json.MarshalIndent(counts, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(filename), fs.ModePerm)
+	if err != nil {
+		return err
+	}
+	tmp := filename + ".tmp"
+	err = os.WriteFile(tmp, append(data, '\n'), 0666)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mixcode/codepage-unzip/codepagezip"
+)
+
+var explainNames = false // print how each output name was made
+
+// the steps taken to make the output name of an entry.
+// Methods do nothing on a nil trace, so that tracing costs nothing when not enabled.
+type nameTrace struct {
+	steps      []string
+	skipReason string // why the entry is skipped; empty if it is not
+}
+
+func newNameTrace(entry *zip.File) *nameTrace {
+	if !explainNames {
+		return nil
+	}
+	t := &nameTrace{}
+	t.add("raw name %q", entry.Name)
+	t.add("decoding: %s", decodingReason(entry))
+	return t
+}
+
+// explain which encoding the name was decoded from
+func decodingReason(entry *zip.File) string {
+	switch {
+	case entry.Flags&FLAG_EFS != 0:
+		return "UTF-8; the EFS flag is set"
+	case !entry.NonUTF8:
+		if isASCII(entry.Name) {
+			return "ASCII; no conversion needed"
+		}
+		return "UTF-8; the EFS flag is not set, but the name is valid UTF-8"
+	}
+	src := "the default"
+	if codepageGiven() {
+		src = "given by -f"
+	}
+	if autoDetected {
+		src = "detected by -f auto"
+	}
+	if !utf8.ValidString(entry.Name) {
+		return fmt.Sprintf("converted from %s (%s)", convertFrom, src)
+	}
+	return fmt.Sprintf("converted from %s (%s); the name is also valid UTF-8, but it may be a coincidence", convertFrom, src)
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *nameTrace) add(format string, a ...any) {
+	if t != nil {
+		t.steps = append(t.steps, fmt.Sprintf(format, a...))
+	}
+}
+
+// record a step if it has changed the name
+func (t *nameTrace) changed(step, before, after string) {
+	if before != after {
+		t.add("%s: %s", step, after)
+	}
+}
+
+// record why the entry is skipped; the first reason is kept
+func (t *nameTrace) skip(format string, a ...any) {
+	if t != nil && t.skipReason == "" {
+		t.skipReason = fmt.Sprintf(format, a...)
+	}
+}
+
+// print the steps, with the sanitization and routing done when writing the file
+func (t *nameTrace) print(name string) {
+	if t == nil {
+		return
+	}
+	if t.skipReason != "" {
+		t.add("skipped: %s", t.skipReason)
+	} else {
+		t.changed("sanitized", strings.TrimRight(name, "/\\"), codepagezip.SanitizePath(name))
+		if dir := routeDir(name); dir != "" {
+			t.add("routed to %s", dir)
+		}
+		t.add("output: %s", path.Join(routeDir(name), codepagezip.SanitizePath(name)))
+	}
+	for i, s := range t.steps {
+		if i == 0 {
+			fmt.Printf("%s\n", s)
+		} else {
+			fmt.Printf("  %s\n", s)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mixcode/codepage-unzip/codepagezip"
+)
+
+var exportFile = "" // write the list of entries to this CSV or XLSX file instead of extracting
+
+var exportHeader = []string{"name", "raw name (hex)", "encoding", "size", "compressed size", "modified", "crc32", "method"}
+
+// the columns of an entry for export
+func exportRow(entry *zip.File, name string) []string {
+	encoding := nameEncoding(entry)
+	if unconverted[entry] {
+		encoding = "unconverted"
+	}
+	return []string{
+		name,
+		hex.EncodeToString([]byte(entry.Name)),
+		encoding,
+		strconv.FormatUint(entry.UncompressedSize64, 10),
+		strconv.FormatUint(entry.CompressedSize64, 10),
+		codepagezip.ModTime(entry, sourceLoc).Format(time.RFC3339),
+		fmt.Sprintf("%08x", entry.CRC32),
+		methodName(entry.Method),
+	}
+}
+
+// write the metadata of the entries to a file; the format is chosen by the extension (.csv or .xlsx)
+func exportEntries(filename string, files []*zip.File, names []string, skip []bool) (err error) {
+	rows := [][]string{exportHeader}
+	for i, f := range files {
+		if !skip[i] {
+			rows = append(rows, exportRow(f, names[i]))
+		}
+	}
+
+	fo, err := os.Create(filename)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if e := fo.Close(); err == nil {
+			err = e
+		}
+		if err != nil {
+			os.Remove(filename)
+		}
+	}()
+	if strings.EqualFold(filepath.Ext(filename), ".xlsx") {
+		return writeXLSX(fo, rows)
+	}
+	w := csv.NewWriter(fo)
+	err = w.WriteAll(rows)
+	return
+}
+
+// write a minimal single-sheet Office Open XML workbook with inline strings
+func writeXLSX(w io.Writer, rows [][]string) (err error) {
+	zw := zip.NewWriter(w)
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`},
+		{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+		{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="entries" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`},
+		{"xl/worksheets/sheet1.xml", sheetXML(rows)},
+	}
+	for _, p := range parts {
+		var fw io.Writer
+		fw, err = zw.Create(p.name)
+		if err != nil {
+			return
+		}
+		_, err = io.WriteString(fw, p.body)
+		if err != nil {
+			return
+		}
+	}
+	return zw.Close()
+}
+
+// the worksheet XML of rows; the size columns (3 and 4) are written as numbers
+func sheetXML(rows [][]string) string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	sb.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for i, row := range rows {
+		fmt.Fprintf(&sb, `<row r="%d">`, i+1)
+		for j, v := range row {
+			if i > 0 && (j == 3 || j == 4) {
+				fmt.Fprintf(&sb, `<c><v>%s</v></c>`, v)
+				continue
+			}
+			sb.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+			xml.EscapeText(&sb, []byte(v))
+			sb.WriteString(`</t></is></c>`)
+		}
+		sb.WriteString(`</row>`)
+	}
+	sb.WriteString(`</sheetData></worksheet>`)
+	return sb.String()
+}
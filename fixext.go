@@ -0,0 +1,122 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+var (
+	fixExtensions = ""                    // -fix-extensions: types to fix, or "all"
+	fixExtTypes   = make(map[string]bool) // enabled types
+)
+
+// a file type recognized by its content
+type magicType struct {
+	ext   string // the extension to append
+	match func(b []byte) bool
+}
+
+func prefix(p string) func([]byte) bool {
+	return func(b []byte) bool { return bytes.HasPrefix(b, []byte(p)) }
+}
+
+func riff(kind string) func([]byte) bool {
+	return func(b []byte) bool { return len(b) >= 12 && string(b[:4]) == "RIFF" && string(b[8:12]) == kind }
+}
+
+var magicTypes = []magicType{
+	{"jpg", prefix("\xff\xd8\xff")},
+	{"png", prefix("\x89PNG\r\n\x1a\n")},
+	{"gif", prefix("GIF8")},
+	{"bmp", prefix("BM")},
+	{"webp", riff("WEBP")},
+	{"pdf", prefix("%PDF-")},
+	{"zip", prefix("PK\x03\x04")},
+	{"gz", prefix("\x1f\x8b")},
+	{"7z", prefix("7z\xbc\xaf\x27\x1c")},
+	{"rar", prefix("Rar!\x1a\x07")},
+	{"mp3", prefix("ID3")},
+	{"ogg", prefix("OggS")},
+	{"flac", prefix("fLaC")},
+	{"wav", riff("WAVE")},
+	{"avi", riff("AVI ")},
+	{"mp4", func(b []byte) bool { return len(b) >= 8 && string(b[4:8]) == "ftyp" }},
+}
+
+// enable the types of -fix-extensions
+func parseFixExtensions(spec string) error {
+	if spec == "" {
+		return nil
+	}
+	known := make(map[string]bool)
+	for _, t := range magicTypes {
+		known[t.ext] = true
+	}
+	for _, s := range strings.Split(spec, ",") {
+		s = strings.ToLower(strings.TrimSpace(s))
+		switch {
+		case s == "all":
+			for t := range known {
+				fixExtTypes[t] = true
+			}
+		case known[s]:
+			fixExtTypes[s] = true
+		case s != "":
+			types := make([]string, 0, len(known))
+			for t := range known {
+				types = append(types, t)
+			}
+			sort.Strings(types)
+			return fmt.Errorf("unknown type '%s' for -fix-extensions (known: %s)", s, strings.Join(types, ","))
+		}
+	}
+	return nil
+}
+
+// report whether an extension is missing or does not look like an extension
+func implausibleExtension(ext string) bool {
+	ext = strings.TrimPrefix(ext, ".")
+	if ext == "" || len(ext) > 5 {
+		return true
+	}
+	for _, c := range ext {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9') {
+			return true
+		}
+	}
+	return false
+}
+
+// sniff the content of an entry whose extension is missing or implausible.
+// Returns the extension to append with a dot, or "" to keep the name.
+func sniffExtension(entry *zip.File, name string) string {
+	if !implausibleExtension(path.Ext(name)) {
+		return ""
+	}
+	r, err := openEntry(entry)
+	if err != nil {
+		return ""
+	}
+	defer r.Close()
+	head := make([]byte, 16)
+	n, _ := io.ReadFull(r, head)
+	head = head[:n]
+	for _, t := range magicTypes {
+		if fixExtTypes[t.ext] && t.match(head) {
+			return "." + t.ext
+		}
+	}
+	return ""
+}
+
+func reportFixedExtension(name, ext string) {
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "Added extension: '%s' -> '%s%s'\n", name, name, ext)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mixcode/codepage-unzip/codepagezip"
+)
+
+// policies for names the destination filesystem may not accept
+const (
+	FsNamesWarn = "warn" // print a warning
+	FsNamesFix  = "fix"  // rewrite the names
+	FsNamesOff  = "off"  // do nothing
+)
+
+var (
+	fsNames      = FsNamesWarn // -fs-names flag
+	windowsNames = false       // avoid names Windows cannot handle even if not extracting on Windows
+)
+
+// check if names must be acceptable on Windows
+func (c fsConstraint) needWindowsNames() bool {
+	return windowsNames || c.windowsSafe || runtime.GOOS == "windows"
+}
+
+// filesystem constraints on names
+type fsConstraint struct {
+	fsType      string
+	windowsSafe bool // no \ : * ? " < > | and no trailing dots or spaces
+	asciiOnly   bool // names are stored in a legacy codepage
+}
+
+// get the name constraints of the filesystem that holds dir
+func destConstraint(dir string) fsConstraint {
+	// find the nearest existing directory
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return fsConstraint{}
+	}
+	for {
+		if _, err := os.Stat(abs); err == nil {
+			break
+		}
+		parent := filepath.Dir(abs)
+		if parent == abs {
+			return fsConstraint{}
+		}
+		abs = parent
+	}
+
+	fsType, options := filesystemOf(abs)
+	c := fsConstraint{fsType: fsType}
+	switch fsType {
+	case "vfat", "msdos", "fat", "exfat", "ntfs", "ntfs3", "fuseblk":
+		c.windowsSafe = true
+	}
+	if fsType == "vfat" || fsType == "msdos" {
+		// vfat keeps unicode names only with the utf8 option or a utf8 iocharset
+		c.asciiOnly = true
+		for _, o := range strings.Split(options, ",") {
+			if o == "utf8" || o == "utf8=1" || o == "utf8=true" || o == "iocharset=utf8" {
+				c.asciiOnly = false
+			}
+		}
+	}
+	return c
+}
+
+// characters not allowed in names on Windows filesystems
+const windowsInvalidChars = `\:*?"<>|`
+
+// make a path component acceptable on Windows filesystems
+func windowsSafeComponent(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(windowsInvalidChars, r) {
+			return '_'
+		}
+		return r
+	}, s)
+	// trailing dots and spaces are silently removed by Windows
+	t := strings.TrimRight(s, ". ")
+	if t != s {
+		t += strings.Repeat("_", len(s)-len(t))
+	}
+	return codepagezip.SanitizeComponent(t)
+}
+
+// make an entry name acceptable on Windows filesystems
+func windowsSafePath(name string) string {
+	comp := strings.Split(codepagezip.SanitizePath(name), "/")
+	for i, c := range comp {
+		comp[i] = windowsSafeComponent(c)
+	}
+	p := strings.Join(comp, "/")
+	if strings.HasSuffix(name, "/") || strings.HasSuffix(name, "\\") {
+		p += "/"
+	}
+	return p
+}
+
+// check if a name is acceptable on the filesystem
+func (c fsConstraint) accepts(name string) bool {
+	if c.asciiOnly {
+		for i := 0; i < len(name); i++ {
+			if name[i] >= 0x80 {
+				return false
+			}
+		}
+	}
+	if c.windowsSafe {
+		for _, comp := range strings.Split(codepagezip.SanitizePath(name), "/") {
+			if windowsSafeComponent(comp) != comp {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// print a warning about names the filesystem may not accept
+func warnFsNames(c fsConstraint, names []string) {
+	bad := 0
+	for _, n := range names {
+		if !c.accepts(n) {
+			bad++
+		}
+	}
+	if bad > 0 {
+		warnf(WarnFs, "the output directory is on a %s filesystem, which may not accept %d of the names (use -fs-names fix)", c.fsType, bad)
+	}
+}
+
+// check if a path component is a Windows reserved device name, with or without an extension
+func isReservedName(s string) bool {
+	stem, _, _ := strings.Cut(s, ".")
+	stem = strings.ToUpper(strings.TrimRight(stem, " "))
+	switch stem {
+	case "CON", "PRN", "AUX", "NUL", "CONIN$", "CONOUT$":
+		return true
+	}
+	if len(stem) == 4 && (strings.HasPrefix(stem, "COM") || strings.HasPrefix(stem, "LPT")) {
+		return stem[3] >= '0' && stem[3] <= '9'
+	}
+	return false
+}
+
+// limits of a single path component; most filesystems count bytes, NTFS and FAT count UTF-16 units
+const (
+	maxComponentBytes  = 255
+	maxComponentUTF16  = 255
+	shortenedHashWidth = 9 // "~" and 8 hex digits
+)
+
+// get the length of a string in UTF-16 units
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// shorten a too long path component, keeping the extension and adding a hash of the full name
+func shortenComponent(s string) string {
+	if len(s) <= maxComponentBytes && utf16Len(s) <= maxComponentUTF16 {
+		return s
+	}
+	ext := filepath.Ext(s)
+	if len(ext) > 32 {
+		ext = ""
+	}
+	stem := s[:len(s)-len(ext)]
+	suffix := fmt.Sprintf("~%08x", crc32.ChecksumIEEE([]byte(s))) + ext
+	for len(stem)+len(suffix) > maxComponentBytes || utf16Len(stem)+utf16Len(suffix) > maxComponentUTF16 {
+		_, n := utf8.DecodeLastRuneInString(stem)
+		stem = stem[:len(stem)-n]
+	}
+	return stem + suffix
+}
+
+// rename path components that cannot be created: too long ones, and Windows reserved names if windows is set
+func limitPath(name string, windows bool) string {
+	trailing := strings.HasSuffix(name, "/") || strings.HasSuffix(name, "\\")
+	comp := strings.Split(codepagezip.SanitizePath(name), "/")
+	changed := false
+	for i, c := range comp {
+		f := shortenComponent(c)
+		if windows && isReservedName(f) {
+			stem, ext, _ := strings.Cut(f, ".")
+			f = stem + "_"
+			if ext != "" {
+				f += "." + ext
+			}
+		}
+		if f != c {
+			comp[i] = f
+			changed = true
+		}
+	}
+	if !changed {
+		return name
+	}
+	p := strings.Join(comp, "/")
+	if trailing {
+		p += "/"
+	}
+	return p
+}
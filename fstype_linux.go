@@ -0,0 +1,62 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// unescape octal sequences like \040 in /proc/self/mountinfo
+func unescapeMountinfo(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// get the type and the options of the filesystem mounted at or above an absolute path
+func filesystemOf(path string) (fsType, options string) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return "", ""
+	}
+	defer f.Close()
+
+	best := ""
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		// 36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+		pre, post, ok := strings.Cut(sc.Text(), " - ")
+		if !ok {
+			continue
+		}
+		fields, pfields := strings.Fields(pre), strings.Fields(post)
+		if len(fields) < 5 || len(pfields) < 3 {
+			continue
+		}
+		mp := unescapeMountinfo(fields[4])
+		if path != mp && !strings.HasPrefix(path, strings.TrimSuffix(mp, "/")+"/") {
+			continue
+		}
+		if len(mp) >= len(best) { // later mounts hide earlier ones
+			best = mp
+			fsType = pfields[0]
+			options = fields[5] + "," + pfields[2]
+		}
+	}
+	return
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// filesystem detection is implemented only on Linux
+func filesystemOf(path string) (fsType, options string) {
+	return "", ""
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+)
+
+// what to fsync
+const (
+	FsyncNever = "never"
+	FsyncFiles = "files" // each extracted file
+	FsyncDirs  = "dirs"  // the directories containing extracted entries, at the end
+	FsyncAll   = "all"   // both
+)
+
+var fsyncPolicy = FsyncNever
+
+// directories to sync at the end
+var dirtyDirs = make(map[string]bool)
+
+func checkFsyncPolicy() error {
+	switch fsyncPolicy {
+	case FsyncNever, FsyncFiles, FsyncDirs, FsyncAll:
+		return nil
+	}
+	return fmt.Errorf("unknown -fsync policy %q", fsyncPolicy)
+}
+
+func syncFiles() bool {
+	return fsyncPolicy == FsyncFiles || fsyncPolicy == FsyncAll
+}
+
+func syncDirs() bool {
+	return fsyncPolicy == FsyncDirs || fsyncPolicy == FsyncAll
+}
+
+// record that an entry has been made in a directory
+func markDirty(outpath string) {
+	if syncDirs() {
+		for dir := filepath.Dir(outpath); !dirtyDirs[dir]; dir = filepath.Dir(dir) {
+			dirtyDirs[dir] = true
+			if !isBeneath(destDir, dir) || dir == filepath.Dir(dir) {
+				// include the parent of the output directory, which may have a new directory
+				break
+			}
+		}
+	}
+}
+
+// fsync the directories recorded by markDirty(), deepest first
+func syncDirtyDirs() error {
+	if runtime.GOOS == "windows" {
+		// directories cannot be synced on Windows; NTFS metadata is journaled
+		return nil
+	}
+	dirs := make([]string, 0, len(dirtyDirs))
+	for dir := range dirtyDirs {
+		dirs = append(dirs, dir)
+	}
+	sort.Slice(dirs, func(i, j int) bool { return len(dirs[i]) > len(dirs[j]) })
+	for _, dir := range dirs {
+		d, err := os.Open(dir)
+		if err != nil {
+			return err
+		}
+		err = d.Sync()
+		d.Close()
+		if err != nil {
+			return fmt.Errorf("fsync %s: %w", dir, err)
+		}
+	}
+	dirtyDirs = make(map[string]bool)
+	return nil
+}
@@ -1,13 +1,15 @@
 module github.com/mixcode/codepage-unzip
 
-go 1.21.0
+go 1.22.0
 
 require (
 	github.com/djimenez/iconv-go v0.0.0-20160305225143-8960e66bd3da
+	github.com/klauspost/compress v1.18.0
 	github.com/mattn/go-tty v0.0.5
+	github.com/ulikunitz/xz v0.5.12
+	golang.org/x/crypto v0.33.0
+	golang.org/x/sys v0.30.0
+	golang.org/x/text v0.22.0
 )
 
-require (
-	github.com/mattn/go-isatty v0.0.10 // indirect
-	golang.org/x/sys v0.0.0-20191120155948-bd437916bb0e // indirect
-)
+require github.com/mattn/go-isatty v0.0.10 // indirect
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+var langName = "" // -lang; the language of messages, or empty to use LANG
+
+// translations of messages by language, keyed by the English message
+var catalogs = map[string]map[string]string{
+	"ja": {
+		"Decompress a ZIP file with non-unicode filenames.\n":                                                                                       "Unicode以外のファイル名を持つZIPファイルを展開します。\n",
+		"Filenames are converted from the specified codepage to unicode.\n":                                                                         "ファイル名は指定したコードページからUnicodeに変換されます。\n",
+		"Run with -list-encodings for the available codepages.\n":                                                                                   "使用できるコードページは -list-encodings で表示できます。\n",
+		"A gzip, bzip2 or xz compressed file may be given instead of a ZIP; the original filename stored in gzip is converted in the same way.\n":   "ZIPの代わりにgzip、bzip2またはxz圧縮ファイルも指定できます。gzipに記録された元のファイル名も同様に変換されます。\n",
+		"An ISO9660 image may be given as well; Joliet names are read as they are, and Rock Ridge or plain ISO9660 names are converted.\n":          "ISO9660イメージも指定できます。Jolietの名前はそのまま読み込まれ、Rock Ridgeまたは通常のISO9660の名前は変換されます。\n",
+		"A ZIP archive may also be given as an http or https URL, from a server supporting range requests; only the parts needed are downloaded.\n": "ZIPアーカイブは、範囲リクエストに対応したサーバーの http または https のURLでも指定できます。必要な部分だけがダウンロードされます。\n",
+		"Flags:\n":                             "フラグ:\n",
+		"Usage:":                               "使い方:",
+		" (default %s)":                        " (既定値 %s)",
+		"The output file '%s' already exists.": "出力ファイル '%s' は既に存在します。",
+		" Overwrite? (y/N)":                    " 上書きしますか? (y/N)",
+		" Overwrite? (y/N/d=diff)":             " 上書きしますか? (y/N/d=差分)",
+		"Error: %v\n":                          "エラー: %v\n",
+		"Warning: ":                            "警告: ",
+		"%d entries could not be extracted":    "%d 個のエントリを展開できませんでした",
+		"a zip filename must be given (use --help for help)":               "ZIPファイル名を指定してください (--help でヘルプを表示)",
+		"%d entries are encrypted and were not extracted\n":                "%d 個のエントリは暗号化されているため展開されませんでした\n",
+		"Waiting for another extraction into %s to finish\n":               "%s への別の展開が終わるのを待っています\n",
+		"another extraction into %s is running (use -wait to wait for it)": "%s への別の展開が実行中です (-wait で待機)",
+		"Archive file":        "アーカイブファイル",
+		"no archive is given": "アーカイブが指定されていません",
+		"All names are in UTF-8; no codepage is needed.\n":              "すべての名前がUTF-8です。コードページは不要です。\n",
+		"%d of %d names are not in UTF-8. Names under each codepage:\n": "%d / %d 個の名前がUTF-8ではありません。コードページごとの名前:\n",
+		"all names convert":                       "すべての名前を変換できます",
+		"%d names cannot be converted":            "%d 個の名前を変換できません",
+		"Codepage number, or a codepage name":     "コードページの番号またはコードページ名",
+		"Destination directory":                   "展開先ディレクトリ",
+		"\nThe command for next time:\n  %s\n\n":  "\n次回用のコマンド:\n  %s\n\n",
+		"Extract now? (Y/n)":                      "今すぐ展開しますか? (Y/n)",
+		"unknown -fs-names policy '%s'":           "不明な -fs-names ポリシー '%s'",
+		"unknown -read-order '%s'":                "不明な -read-order '%s'",
+		"unknown -dir-data policy '%s'":           "不明な -dir-data ポリシー '%s'",
+		"the destination path is not a directory": "展開先のパスがディレクトリではありません",
+	},
+	"ko": {
+		"Decompress a ZIP file with non-unicode filenames.\n":                                                                                       "유니코드가 아닌 파일 이름을 가진 ZIP 파일의 압축을 풉니다.\n",
+		"Filenames are converted from the specified codepage to unicode.\n":                                                                         "파일 이름은 지정한 코드 페이지에서 유니코드로 변환됩니다.\n",
+		"Run with -list-encodings for the available codepages.\n":                                                                                   "사용 가능한 코드 페이지는 -list-encodings로 볼 수 있습니다.\n",
+		"A gzip, bzip2 or xz compressed file may be given instead of a ZIP; the original filename stored in gzip is converted in the same way.\n":   "ZIP 대신 gzip, bzip2 또는 xz 압축 파일을 지정할 수도 있습니다. gzip에 저장된 원래 파일 이름도 같은 방식으로 변환됩니다.\n",
+		"An ISO9660 image may be given as well; Joliet names are read as they are, and Rock Ridge or plain ISO9660 names are converted.\n":          "ISO9660 이미지도 지정할 수 있습니다. Joliet 이름은 그대로 읽고, Rock Ridge 또는 일반 ISO9660 이름은 변환됩니다.\n",
+		"A ZIP archive may also be given as an http or https URL, from a server supporting range requests; only the parts needed are downloaded.\n": "ZIP 아카이브는 범위 요청을 지원하는 서버의 http 또는 https URL로도 지정할 수 있습니다. 필요한 부분만 내려받습니다.\n",
+		"Flags:\n":                             "플래그:\n",
+		"Usage:":                               "사용법:",
+		" (default %s)":                        " (기본값 %s)",
+		"The output file '%s' already exists.": "출력 파일 '%s'이(가) 이미 있습니다.",
+		" Overwrite? (y/N)":                    " 덮어쓸까요? (y/N)",
+		" Overwrite? (y/N/d=diff)":             " 덮어쓸까요? (y/N/d=차이)",
+		"Error: %v\n":                          "오류: %v\n",
+		"Warning: ":                            "경고: ",
+		"%d entries could not be extracted":    "%d개 항목을 압축 해제하지 못했습니다",
+		"a zip filename must be given (use --help for help)":               "ZIP 파일 이름을 지정해야 합니다 (--help로 도움말 보기)",
+		"%d entries are encrypted and were not extracted\n":                "%d개 항목이 암호화되어 있어 압축 해제되지 않았습니다\n",
+		"Waiting for another extraction into %s to finish\n":               "%s에 대한 다른 압축 해제가 끝나기를 기다리는 중\n",
+		"another extraction into %s is running (use -wait to wait for it)": "%s에 대한 다른 압축 해제가 실행 중입니다 (-wait로 대기)",
+		"Archive file":        "아카이브 파일",
+		"no archive is given": "아카이브가 지정되지 않았습니다",
+		"All names are in UTF-8; no codepage is needed.\n":              "모든 이름이 UTF-8입니다. 코드 페이지가 필요 없습니다.\n",
+		"%d of %d names are not in UTF-8. Names under each codepage:\n": "%d / %d개 이름이 UTF-8이 아닙니다. 코드 페이지별 이름:\n",
+		"all names convert":                       "모든 이름을 변환할 수 있음",
+		"%d names cannot be converted":            "%d개 이름을 변환할 수 없음",
+		"Codepage number, or a codepage name":     "코드 페이지 번호 또는 코드 페이지 이름",
+		"Destination directory":                   "출력 디렉터리",
+		"\nThe command for next time:\n  %s\n\n":  "\n다음에 쓸 명령:\n  %s\n\n",
+		"Extract now? (Y/n)":                      "지금 압축을 풀까요? (Y/n)",
+		"unknown -fs-names policy '%s'":           "알 수 없는 -fs-names 정책 '%s'",
+		"unknown -read-order '%s'":                "알 수 없는 -read-order '%s'",
+		"unknown -dir-data policy '%s'":           "알 수 없는 -dir-data 정책 '%s'",
+		"the destination path is not a directory": "출력 경로가 디렉터리가 아닙니다",
+	},
+	"zh": {
+		"Decompress a ZIP file with non-unicode filenames.\n":                                                                                       "解压文件名不是 Unicode 的 ZIP 文件。\n",
+		"Filenames are converted from the specified codepage to unicode.\n":                                                                         "文件名将从指定的代码页转换为 Unicode。\n",
+		"Run with -list-encodings for the available codepages.\n":                                                                                   "可用的代码页可以用 -list-encodings 查看。\n",
+		"A gzip, bzip2 or xz compressed file may be given instead of a ZIP; the original filename stored in gzip is converted in the same way.\n":   "也可以指定 gzip、bzip2 或 xz 压缩文件代替 ZIP；gzip 中保存的原始文件名也会以同样方式转换。\n",
+		"An ISO9660 image may be given as well; Joliet names are read as they are, and Rock Ridge or plain ISO9660 names are converted.\n":          "也可以指定 ISO9660 映像；Joliet 名称按原样读取，Rock Ridge 或普通 ISO9660 名称会被转换。\n",
+		"A ZIP archive may also be given as an http or https URL, from a server supporting range requests; only the parts needed are downloaded.\n": "也可以用支持范围请求的服务器上的 http 或 https URL 指定 ZIP 归档；只下载需要的部分。\n",
+		"Flags:\n":                             "选项:\n",
+		"Usage:":                               "用法:",
+		" (default %s)":                        "（默认 %s）",
+		"The output file '%s' already exists.": "输出文件 '%s' 已存在。",
+		" Overwrite? (y/N)":                    " 覆盖吗? (y/N)",
+		" Overwrite? (y/N/d=diff)":             " 覆盖吗? (y/N/d=差异)",
+		"Error: %v\n":                          "错误: %v\n",
+		"Warning: ":                            "警告: ",
+		"%d entries could not be extracted":    "%d 个条目无法解压",
+		"a zip filename must be given (use --help for help)":               "必须指定 ZIP 文件名 (使用 --help 查看帮助)",
+		"%d entries are encrypted and were not extracted\n":                "%d 个条目已加密，未解压\n",
+		"Waiting for another extraction into %s to finish\n":               "正在等待另一个解压到 %s 的进程结束\n",
+		"another extraction into %s is running (use -wait to wait for it)": "另一个解压到 %s 的进程正在运行 (使用 -wait 等待)",
+		"Archive file":        "压缩文件",
+		"no archive is given": "未指定压缩文件",
+		"All names are in UTF-8; no codepage is needed.\n":              "所有名称都是 UTF-8，不需要代码页。\n",
+		"%d of %d names are not in UTF-8. Names under each codepage:\n": "%d / %d 个名称不是 UTF-8。各代码页下的名称:\n",
+		"all names convert":                       "所有名称都能转换",
+		"%d names cannot be converted":            "%d 个名称无法转换",
+		"Codepage number, or a codepage name":     "代码页编号或代码页名称",
+		"Destination directory":                   "输出目录",
+		"\nThe command for next time:\n  %s\n\n":  "\n下次使用的命令:\n  %s\n\n",
+		"Extract now? (Y/n)":                      "现在解压吗? (Y/n)",
+		"unknown -fs-names policy '%s'":           "未知的 -fs-names 策略 '%s'",
+		"unknown -read-order '%s'":                "未知的 -read-order '%s'",
+		"unknown -dir-data policy '%s'":           "未知的 -dir-data 策略 '%s'",
+		"the destination path is not a directory": "输出路径不是目录",
+	},
+	"ru": {
+		"Decompress a ZIP file with non-unicode filenames.\n":                                                                                       "Распаковка ZIP-файлов с именами файлов не в Юникоде.\n",
+		"Filenames are converted from the specified codepage to unicode.\n":                                                                         "Имена файлов преобразуются из указанной кодовой страницы в Юникод.\n",
+		"Run with -list-encodings for the available codepages.\n":                                                                                   "Доступные кодовые страницы можно вывести с помощью -list-encodings.\n",
+		"A gzip, bzip2 or xz compressed file may be given instead of a ZIP; the original filename stored in gzip is converted in the same way.\n":   "Вместо ZIP можно указать файл, сжатый gzip, bzip2 или xz; исходное имя файла, сохранённое в gzip, преобразуется так же.\n",
+		"An ISO9660 image may be given as well; Joliet names are read as they are, and Rock Ridge or plain ISO9660 names are converted.\n":          "Можно указать и образ ISO9660; имена Joliet читаются как есть, а имена Rock Ridge или обычные имена ISO9660 преобразуются.\n",
+		"A ZIP archive may also be given as an http or https URL, from a server supporting range requests; only the parts needed are downloaded.\n": "ZIP-архив можно указать и URL http или https на сервере, поддерживающем запросы диапазонов; скачиваются только нужные части.\n",
+		"Flags:\n":                             "Флаги:\n",
+		"Usage:":                               "Использование:",
+		" (default %s)":                        " (по умолчанию %s)",
+		"The output file '%s' already exists.": "Выходной файл '%s' уже существует.",
+		" Overwrite? (y/N)":                    " Перезаписать? (y/N)",
+		" Overwrite? (y/N/d=diff)":             " Перезаписать? (y/N/d=различия)",
+		"Error: %v\n":                          "Ошибка: %v\n",
+		"Warning: ":                            "Предупреждение: ",
+		"%d entries could not be extracted":    "не удалось извлечь записей: %d",
+		"a zip filename must be given (use --help for help)":               "необходимо указать имя ZIP-файла (справка: --help)",
+		"%d entries are encrypted and were not extracted\n":                "записей зашифровано и не извлечено: %d\n",
+		"Waiting for another extraction into %s to finish\n":               "Ожидание завершения другой распаковки в %s\n",
+		"another extraction into %s is running (use -wait to wait for it)": "уже идёт другая распаковка в %s (используйте -wait для ожидания)",
+		"Archive file":        "Файл архива",
+		"no archive is given": "архив не указан",
+		"All names are in UTF-8; no codepage is needed.\n":              "Все имена в UTF-8; кодовая страница не нужна.\n",
+		"%d of %d names are not in UTF-8. Names under each codepage:\n": "Имён не в UTF-8: %d из %d. Имена в каждой кодовой странице:\n",
+		"all names convert":                       "все имена преобразуются",
+		"%d names cannot be converted":            "не удаётся преобразовать имён: %d",
+		"Codepage number, or a codepage name":     "Номер или имя кодовой страницы",
+		"Destination directory":                   "Каталог назначения",
+		"\nThe command for next time:\n  %s\n\n":  "\nКоманда на следующий раз:\n  %s\n\n",
+		"Extract now? (Y/n)":                      "Распаковать сейчас? (Y/n)",
+		"unknown -fs-names policy '%s'":           "неизвестная политика -fs-names '%s'",
+		"unknown -read-order '%s'":                "неизвестный -read-order '%s'",
+		"unknown -dir-data policy '%s'":           "неизвестная политика -dir-data '%s'",
+		"the destination path is not a directory": "путь назначения не является каталогом",
+	},
+}
+
+// the language of messages: -lang, or the locale environment variables
+func messageLang() string {
+	lang := langName
+	if lang == "" {
+		// usage is printed while parsing flags, before -lang is set
+		for i, a := range os.Args[1:] {
+			if v, ok := strings.CutPrefix(strings.TrimLeft(a, "-"), "lang="); ok && strings.HasPrefix(a, "-") {
+				lang = v
+			} else if (a == "-lang" || a == "--lang") && i+2 < len(os.Args) {
+				lang = os.Args[i+2]
+			}
+		}
+	}
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if lang != "" {
+			break
+		}
+		lang = os.Getenv(env)
+	}
+	lang, _, _ = strings.Cut(strings.ToLower(lang), "_")
+	lang, _, _ = strings.Cut(lang, ".")
+	lang, _, _ = strings.Cut(lang, "-")
+	return lang
+}
+
+// translate a message into the language of messages; messages without a translation are kept in English
+func tr(msg string) string {
+	if t, ok := catalogs[messageLang()][msg]; ok {
+		return t
+	}
+	return msg
+}
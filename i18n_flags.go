@@ -0,0 +1,294 @@
+package main
+
+// translations of the flag descriptions by language, keyed by the flag name;
+// a description without a translation is printed in English
+var flagCatalogs = map[string]map[string]string{
+	"ja": {
+		"l":                  "展開せずにファイル名を表示する",
+		"d":                  "ファイルを展開するディレクトリ",
+		"dest-per-archive":   "各アーカイブを、このテンプレートから作った -d 以下のディレクトリに展開する。例: '{dir}/{base}'。{dir}、{base}、{name}、{ext} はアーカイブのディレクトリ、拡張子を除いた名前、名前、拡張子に置き換えられる",
+		"marker":             "展開したアーカイブを展開先の " + markerFilename + " ファイルに記録し、同じ内容とオプションで記録済みのアーカイブをスキップする",
+		"archives-from-0":    "このファイルに find -print0 のようにNUL文字区切りで列挙されたアーカイブも処理する。'-' は標準入力",
+		"manifest":           "census のCSVレポートに列挙されたアーカイブも、それぞれ記載のコードページで処理する。誤りは codepage 列を編集して直し、空にすると -f を使う",
+		"jobs":               "一度にこの数のアーカイブを、それぞれ別のプロセスで処理する。失敗したアーカイブは他に影響しない。-threads を指定しなければ、デコードのスレッド数もこの数を分け合う",
+		"o":                  "既存のファイルを上書きする",
+		"symlink-policy":     "シンボリックリンクの展開方法: auto、link、junction (Windowsのディレクトリ)、hardlink、copy、skip、または file (リンク先のパスを書いたファイル)",
+		"ads":                "file.txt:stream のような名前のNTFS代替データストリームの展開方法: auto (Windowsではストリーム、それ以外ではサイドカー。ファイル本体もアーカイブにある名前のみ)、stream、sidecar (file.txt_stream という名前のファイル)、または skip",
+		"mac-forks":          "Macのリソースフォークと Finder 情報を持つ AppleDouble の ._name エントリ (通常は __MACOSX 以下) の展開方法: keep (アーカイブの名前のまま)、auto (macOSでは復元、それ以外ではサイドカー)、restore (macOSのみ)、sidecar (ファイルの隣の ._name)、または skip",
+		"specials":           "FIFO、デバイス、ソケットのエントリの展開方法: skip、error、または create",
+		"backup-existing":    "上書きされるファイルを相対パスを保ったままバックアップディレクトリに移動する。ディレクトリは -backup-existing=DIR で指定",
+		"k":                  "整理して展開する。ZIPファイルと同じ名前のサブディレクトリを作り、その中にファイルを置く",
+		"dir-data":           "名前がスラッシュで終わるのにデータを持つエントリの扱い: dir (データを無視)、file (ファイルとして書く)、または error",
+		"k-policy":           "-k のサブディレクトリが既にあり空でないときの扱い: merge、suffix、または error",
+		"no-lock":            "展開先ディレクトリを他の展開からロックしない",
+		"wait":               "同じ展開先への別の展開があるとき、失敗せずに終了を待つ",
+		"staging":            "一時ディレクトリに展開し、すべて終わってから所定の場所に移動する",
+		"sandbox":            "(Linuxのみ) openat2() を使って展開のすべての書き込みを展開先ディレクトリ内に制限し、デバイス、FIFO、setuid のエントリを拒否する。-staging とは併用できない",
+		"max-entries":        "エントリ数がこれを超えるアーカイブを拒否する (0 で無制限)",
+		"max-depth":          "パスの階層がこれより深いエントリを拒否する (0 で無制限)",
+		"read-order":         "エントリを展開する順序: cd (セントラルディレクトリの順) または offset (ファイル内の格納順。順次読み込み向け)",
+		"source-tz":          "アーカイブが作られたタイムゾーン (例: Asia/Tokyo)。DOSタイムスタンプしか持たないエントリの更新日時に使う (既定はローカルのゾーン)",
+		"dirs-only":          "ファイルを除き、ディレクトリ構造だけを作る",
+		"update":             "存在しないファイルと、エントリより古いファイルだけを展開し、古いものを置き換える。DOSタイムスタンプの2秒の精度以内、または夏時間による1時間差の時刻は同じとみなす",
+		"since":              "このアーカイブの旧版から新しく追加または変更された (名前とCRCで判断) エントリだけを展開する",
+		"small-first":        "小さいエントリから先に展開する",
+		"checkpoint":         "完了したエントリをこのファイルに記録し、完了と記録されたエントリをスキップする (中断した展開の再開用)",
+		"write-retries":      "ウイルススキャナの検査中などにシステムが使用中と報告したとき、ファイルの作成を再試行する回数",
+		"preallocate":        "書き込む前に各ファイルの全サイズを確保し、断片化を減らしてディスク不足で早めに失敗する (Linux、macOS、FreeBSD、Windows のみ。他では無視)",
+		"fsync":              "クラッシュ耐性のために fsync するもの: never、files (展開した各ファイル)、dirs (新しいエントリのあるディレクトリ)、または all",
+		"zero-copy":          "無圧縮 (stored) のエントリをZIPファイルから直接コピーする。高速だがCRCは検証されない",
+		"threads":            "Zstandard圧縮のエントリをデコードするスレッド数 (0 でCPU数)",
+		"warnings-as-errors": "変換できない名前、疑わしい名前、スキップしたエントリ、ありえないタイムスタンプなどの警告があれば失敗する",
+		"keep-going":         "展開できないエントリを報告して残りを続ける",
+		"entry-timeout":      "データの読み込みがこの時間止まったらエントリを諦める (例: 30s。0 でタイムアウトなし)",
+		"route":              "拡張子ごとにファイルをサブディレクトリに振り分ける。例: 'jpg,png=images/;txt=docs/'",
+		"ascii-slugs":        "出力名をASCIIのみの名前に翻字し、対応表を " + slugsMapFilename + " に書く",
+		"translit":           "-ascii-slugs と translit で使う翻字方式を優先順に (hepburn: 日本語のかな、rr: 韓国語、iso9: キリル文字、latin: ダイアクリティカルマーク、pinyin: 漢字の中国語読み。既定では使わない)",
+		"fs-names":           "展開先がFATまたはNTFSファイルシステムのとき: 受け付けられない可能性のある名前を警告する (warn)、修正する (fix)、または off",
+		"preset":             "命名オプションのまとめを適用する: " + presetNames() + "。明示的に指定したオプションが優先される",
+		"fix-extensions":     "拡張子がない、または内容に合わないファイルに、検出した内容の種類の拡張子を付ける。検出する種類のカンマ区切りリスト (例: jpg,png,pdf)、または 'all'",
+		"strip-controls":     "名前から制御文字、双方向オーバーライド、ゼロ幅文字を取り除く",
+		"width-fold":         "全角の英数字と記号をASCIIに、半角カタカナを全角に変換する",
+		"windows-names":      "Windows や FAT/NTFS ファイルシステムへの展開でなくても、CON や NUL.txt のようなWindowsの予約名を変更する",
+		"transform-cmd":      "エントリの名前を変更またはスキップする外部コマンド。標準入力からエントリごとにJSONの要求を読み、1行ずつJSONの応答を書く",
+		"wizard":             "アーカイブ、コードページ、展開先を名前のプレビューを見ながら対話的に選ぶ",
+		"q":                  "メッセージを抑制する",
+		"lang":               "メッセージの言語: en、ja、ko、zh、または ru (既定は LANG から)。使い方、確認、主なメッセージが翻訳され、その他のエラーは英語のまま",
+		"explain-names":      "各エントリの出力名がどう作られたかを表示する: デコード、変換コマンド、スラッグ、サニタイズ、振り分け",
+		"export":             "展開せずに、エントリの名前、サイズ、日時、CRC、エンコーディングをこのファイルに書く。CSV、または名前が .xlsx で終われば XLSX",
+		"password-list":      "展開せずに、このファイルの各パスワード (1行に1つ) を暗号化されたエントリに試し、どれで開けるかを報告する",
+		"cache-dir":          "アーカイブが http または https のURLで指定されたとき、読み込んだ部分をURLとETagごとにこのディレクトリに保存し、再び一覧表示や展開をするときに再ダウンロードしない",
+		"list-cache":         "-l のとき、一覧をアーカイブの内容とオプションごとにキャッシュし、次回はキャッシュした一覧を表示する",
+		"convert-content":    "これらの拡張子のファイルの内容を -f から -t に変換する。例: 'txt,csv' (すべてのファイルは '*')",
+		"scan-text":          "展開後、内容が有効なUTF-8でない、または置換文字を含むテキストファイルを報告する",
+		"stats":              "展開後、圧縮方式別と名前のエンコーディング別の統計を表示する",
+		"encoding-stats":     "UTF-8でない名前を持つ各アーカイブで使ったコードページとその決め方を、ユーザー設定ディレクトリの encoding-stats.json に数える。どこにも送信されない",
+		"names-map":          "展開した各エントリの生の名前、エンコーディング、出力パスを記録する " + namesMapFilename + " ファイルを書く",
+		"encrypt":            "add: 新しいエントリをWinZip AES-256で暗号化する。既存のエントリはそのままコピーされる",
+		"password":           "add: -encrypt のパスワード。システムの他のユーザーがプロセス一覧で見られることがある",
+		"set-comment":        "comment: アーカイブのコメント、または指定したエントリのコメントを設定する",
+		"transcode-comments": "comment: アーカイブとエントリのコメントを -f から -t に変換する",
+		"census-format":      "census: レポートの形式。csv または json",
+		"list-encodings":     "使用できるコードページとその名前を表示して終了する",
+		"f":                  "ZIP内のファイル名のコードページ。'auto' で名前から検出する",
+		"t":                  "出力ファイル名のコードページ。警告: 何をしているか正確にわかっている場合以外は変更しないこと!",
+	},
+	"ko": {
+		"l":                  "압축을 풀지 않고 파일 이름을 출력",
+		"d":                  "파일의 압축을 풀 디렉터리",
+		"dest-per-archive":   "각 아카이브를 이 템플릿으로 만든 -d 아래의 디렉터리에 압축 해제 (예: '{dir}/{base}'). {dir}, {base}, {name}, {ext}는 아카이브의 디렉터리, 확장자를 뺀 이름, 이름, 확장자로 바뀜",
+		"marker":             "압축을 푼 아카이브를 대상의 " + markerFilename + " 파일에 기록하고, 같은 내용과 옵션으로 기록된 아카이브는 건너뜀",
+		"archives-from-0":    "이 파일에 find -print0처럼 NUL 문자로 구분해 나열된 아카이브도 처리. '-'는 표준 입력",
+		"manifest":           "census의 CSV 보고서에 나열된 아카이브도 각각 적힌 코드 페이지로 처리. 틀린 것은 codepage 열을 고치고, 비우면 -f를 사용",
+		"jobs":               "한 번에 이 수만큼의 아카이브를 각각 별도의 프로세스에서 처리하므로 실패한 아카이브가 다른 것에 영향을 주지 않음. -threads를 주지 않으면 디코딩 스레드 수도 이 수를 나누어 씀",
+		"o":                  "기존 파일을 덮어씀",
+		"symlink-policy":     "심볼릭 링크를 푸는 방법: auto, link, junction (Windows 디렉터리), hardlink, copy, skip, 또는 file (대상 경로를 담은 파일)",
+		"ads":                "file.txt:stream 같은 이름의 NTFS 대체 데이터 스트림을 푸는 방법: auto (Windows에서는 스트림, 그 외에는 사이드카; 파일 자체도 아카이브에 있는 이름만), stream, sidecar (file.txt_stream 이름의 파일), 또는 skip",
+		"mac-forks":          "Mac 리소스 포크와 Finder 정보를 담은 AppleDouble ._name 항목 (보통 __MACOSX 아래)을 푸는 방법: keep (아카이브의 이름 그대로), auto (macOS에서는 복원, 그 외에는 사이드카), restore (macOS 전용), sidecar (파일 옆의 ._name), 또는 skip",
+		"specials":           "FIFO, 장치, 소켓 항목을 푸는 방법: skip, error, 또는 create",
+		"backup-existing":    "덮어쓸 파일을 상대 경로를 유지한 채 백업 디렉터리로 옮김. 디렉터리는 -backup-existing=DIR로 지정",
+		"k":                  "정리해서 풀기: ZIP 파일과 같은 이름의 하위 디렉터리를 만들고 그 안에 파일을 둠",
+		"dir-data":           "이름이 슬래시로 끝나지만 데이터가 있는 항목의 처리: dir (데이터 무시), file (파일로 씀), 또는 error",
+		"k-policy":           "-k의 하위 디렉터리가 이미 있고 비어 있지 않을 때의 처리: merge, suffix, 또는 error",
+		"no-lock":            "출력 디렉터리를 다른 압축 해제에 대해 잠그지 않음",
+		"wait":               "같은 출력 디렉터리로의 다른 압축 해제가 있으면 실패하지 않고 끝나기를 기다림",
+		"staging":            "임시 디렉터리에 푼 다음 모두 끝났을 때만 제자리로 옮김",
+		"sandbox":            "(Linux 전용) openat2()로 압축 해제의 모든 쓰기를 출력 디렉터리 안으로 제한하고, 장치, FIFO, setuid 항목을 거부. -staging과 함께 쓸 수 없음",
+		"max-entries":        "항목이 이보다 많은 아카이브를 거부 (0은 제한 없음)",
+		"max-depth":          "경로 단계가 이보다 깊은 항목을 거부 (0은 제한 없음)",
+		"read-order":         "항목을 푸는 순서: cd (중앙 디렉터리 순서) 또는 offset (파일에 저장된 순서, 순차 읽기용)",
+		"source-tz":          "아카이브를 만든 시간대 (예: Asia/Tokyo). DOS 타임스탬프만 있는 항목의 수정 시각에 사용 (기본값은 로컬 시간대)",
+		"dirs-only":          "파일 없이 디렉터리 구조만 만듦",
+		"update":             "없는 파일과 항목보다 오래된 파일만 풀고 오래된 것을 교체. DOS 타임스탬프의 2초 정밀도 이내이거나 일광 절약 시간으로 1시간 차이 나는 시각은 같은 것으로 봄",
+		"since":              "이 아카이브의 이전 버전 이후 새로 생기거나 바뀐 (이름과 CRC로 판단) 항목만 풂",
+		"small-first":        "작은 항목을 먼저 풂",
+		"checkpoint":         "완료한 항목을 이 파일에 기록하고, 완료로 기록된 항목은 건너뜀 (중단된 압축 해제 재개용)",
+		"write-retries":      "바이러스 검사 중처럼 시스템이 사용 중이라고 보고할 때 파일 만들기를 다시 시도하는 횟수",
+		"preallocate":        "쓰기 전에 각 파일의 전체 크기를 확보해 조각화를 줄이고, 디스크가 가득 차면 일찍 실패 (Linux, macOS, FreeBSD, Windows 전용, 그 밖에서는 무시)",
+		"fsync":              "충돌 내구성을 위해 fsync할 대상: never, files (푼 각 파일), dirs (새 항목이 있는 디렉터리), 또는 all",
+		"zero-copy":          "압축하지 않은 (stored) 항목을 ZIP 파일에서 바로 복사. 빠르지만 CRC를 검증하지 않음",
+		"threads":            "Zstandard로 압축된 항목을 디코딩할 스레드 수 (0은 CPU 수)",
+		"warnings-as-errors": "변환할 수 없는 이름, 의심스러운 이름, 건너뛴 항목, 비정상적인 타임스탬프 같은 경고가 있으면 실패",
+		"keep-going":         "풀 수 없는 항목을 보고하고 나머지를 계속 진행",
+		"entry-timeout":      "데이터 읽기가 이 시간 동안 멈추면 항목을 포기 (예: 30s; 0은 시간 제한 없음)",
+		"route":              "확장자별로 파일을 하위 디렉터리에 나눔 (예: 'jpg,png=images/;txt=docs/')",
+		"ascii-slugs":        "출력 이름을 ASCII만으로 된 이름으로 음역하고 대응표를 " + slugsMapFilename + "에 씀",
+		"translit":           "-ascii-slugs와 translit에서 쓸 음역 방식, 우선순위 순 (hepburn: 일본어 가나, rr: 한국어, iso9: 키릴 문자, latin: 발음 구별 부호, pinyin: 한자의 중국어 발음. 기본값에는 없음)",
+		"fs-names":           "출력 디렉터리가 FAT 또는 NTFS 파일 시스템일 때: 받아들이지 못할 수 있는 이름을 경고 (warn), 수정 (fix), 또는 off",
+		"preset":             "이름 옵션 묶음을 적용: " + presetNames() + ". 명시적으로 지정한 옵션이 우선",
+		"fix-extensions":     "확장자가 없거나 맞지 않는 파일에 감지한 내용 형식의 확장자를 붙임. 감지할 형식의 쉼표 구분 목록 (예: jpg,png,pdf), 또는 'all'",
+		"strip-controls":     "이름에서 제어 문자, 양방향 재정의 문자, 폭 없는 문자를 제거",
+		"width-fold":         "전각 영문자, 숫자, 기호를 ASCII로, 반각 가타카나를 전각으로 변환",
+		"windows-names":      "Windows나 FAT/NTFS 파일 시스템으로 풀지 않을 때도 CON이나 NUL.txt 같은 Windows 예약 이름을 바꿈",
+		"transform-cmd":      "항목 이름을 바꾸거나 건너뛰는 외부 명령. 표준 입력에서 항목마다 JSON 요청을 읽고 한 줄씩 JSON 응답을 씀",
+		"wizard":             "이름 미리보기를 보며 아카이브, 코드 페이지, 대상을 대화식으로 선택",
+		"q":                  "메시지를 표시하지 않음",
+		"lang":               "메시지 언어: en, ja, ko, zh, 또는 ru (기본값은 LANG에서). 사용법, 확인 질문, 주요 메시지가 번역되며 그 밖의 오류는 영어로 표시",
+		"explain-names":      "각 항목의 출력 이름이 어떻게 만들어졌는지 출력: 디코딩, 변환 명령, 슬러그, 정리, 분류",
+		"export":             "압축을 풀지 않고 항목의 이름, 크기, 날짜, CRC, 인코딩을 이 파일에 씀. CSV, 또는 이름이 .xlsx로 끝나면 XLSX",
+		"password-list":      "압축을 풀지 않고 이 파일의 각 암호 (한 줄에 하나)를 암호화된 항목에 시도해 어떤 것으로 열리는지 보고",
+		"cache-dir":          "아카이브가 http 또는 https URL로 주어졌을 때 읽은 부분을 URL과 ETag별로 이 디렉터리에 저장해, 다시 목록을 보거나 압축을 풀 때 다시 내려받지 않음",
+		"list-cache":         "-l과 함께 쓰면 목록을 아카이브 내용과 옵션별로 캐시하고 다음에는 캐시된 목록을 출력",
+		"convert-content":    "이 확장자들을 가진 파일의 내용을 -f에서 -t로 변환 (예: 'txt,csv'; 모든 파일은 '*')",
+		"scan-text":          "압축을 푼 뒤 내용이 올바른 UTF-8이 아니거나 대체 문자를 포함한 텍스트 파일을 보고",
+		"stats":              "압축을 푼 뒤 압축 방식별, 이름 인코딩별 통계를 출력",
+		"encoding-stats":     "UTF-8가 아닌 이름을 가진 각 아카이브에 쓴 코드 페이지와 그것을 정한 방법을 사용자 설정 디렉터리의 encoding-stats.json에 집계. 어디에도 전송하지 않음",
+		"names-map":          "푼 각 항목의 원래 이름, 인코딩, 출력 경로를 기록한 " + namesMapFilename + " 파일을 씀",
+		"encrypt":            "add: 새 항목을 WinZip AES-256으로 암호화. 기존 항목은 그대로 복사됨",
+		"password":           "add: -encrypt의 암호. 시스템의 다른 사용자가 프로세스 목록에서 볼 수 있음",
+		"set-comment":        "comment: 아카이브의 주석 또는 지정한 항목의 주석을 설정",
+		"transcode-comments": "comment: 아카이브와 항목의 주석을 -f에서 -t로 변환",
+		"census-format":      "census: 보고서 형식, csv 또는 json",
+		"list-encodings":     "사용 가능한 코드 페이지와 그 이름을 출력하고 종료",
+		"f":                  "ZIP 안 파일 이름의 코드 페이지. 'auto'는 이름으로 감지",
+		"t":                  "출력 파일 이름의 코드 페이지. 경고: 무엇을 하는지 정확히 알 때만 바꿀 것!",
+	},
+	"zh": {
+		"l":                  "只列出文件名，不解压",
+		"d":                  "解压文件的目标目录",
+		"dest-per-archive":   "将每个归档解压到 -d 下由此模板生成的目录，例如 '{dir}/{base}'；{dir}、{base}、{name} 和 {ext} 分别替换为归档的目录、不含扩展名的名称、名称和扩展名",
+		"marker":             "在目标目录的 " + markerFilename + " 文件中记录已解压的归档，并跳过以相同内容和选项记录过的归档",
+		"archives-from-0":    "同时处理此文件中以 NUL 字符分隔列出的归档（如 find -print0 的输出）；'-' 表示标准输入",
+		"manifest":           "同时处理 census 的 CSV 报告中列出的归档，各自使用其中给出的代码页；可编辑 codepage 列来更正，留空则使用 -f",
+		"jobs":               "同时处理这么多个归档，每个在各自的进程中，失败的归档不会影响其他归档；未给出 -threads 时，它们也共享这么多个解码线程",
+		"o":                  "覆盖已有文件",
+		"symlink-policy":     "符号链接的解压方式：auto、link、junction（Windows 目录）、hardlink、copy、skip 或 file（包含目标路径的文件）",
+		"ads":                "名为 file.txt:stream 的 NTFS 备用数据流的解压方式：auto（Windows 上为数据流，其他系统为附属文件；仅限其文件本身也在归档中的名称）、stream、sidecar（名为 file.txt_stream 的文件）或 skip",
+		"mac-forks":          "包含 Mac 资源分支和 Finder 信息的 AppleDouble ._name 条目（通常位于 __MACOSX 下）的解压方式：keep（保持归档中的名称）、auto（macOS 上恢复，其他系统为附属文件）、restore（仅 macOS）、sidecar（文件旁的 ._name）或 skip",
+		"specials":           "FIFO、设备和套接字条目的解压方式：skip、error 或 create",
+		"backup-existing":    "将被覆盖的文件按相对路径移入备份目录；用 -backup-existing=DIR 指定目录",
+		"k":                  "整理解压：创建与 ZIP 文件同名的子目录，并把文件放在其中",
+		"dir-data":           "名称以斜杠结尾却带有数据的条目的处理方式：dir（忽略数据）、file（写为文件）或 error",
+		"k-policy":           "-k 的子目录已存在且非空时的处理方式：merge、suffix 或 error",
+		"no-lock":            "不锁定输出目录以防止其他解压",
+		"wait":               "有其他解压正在写入同一输出目录时，等待其完成而不是失败",
+		"staging":            "先解压到临时目录，全部完成后再移动到目标位置",
+		"sandbox":            "（仅 Linux）使用 openat2() 将解压的所有写入限制在输出目录内，并拒绝设备、FIFO 和 setuid 条目；不能与 -staging 同用",
+		"max-entries":        "拒绝条目数超过此值的归档（0 表示不限）",
+		"max-depth":          "拒绝路径层级超过此值的条目（0 表示不限）",
+		"read-order":         "解压条目的顺序：cd（按中央目录列出的顺序）或 offset（按文件中的存储顺序，用于顺序读取）",
+		"source-tz":          "创建归档时的时区，例如 Asia/Tokyo，用于只有 DOS 时间戳的条目的修改时间（默认为本地时区）",
+		"dirs-only":          "只创建目录结构，不解压文件",
+		"update":             "只解压不存在或比条目旧的文件，并替换旧文件；在 DOS 时间戳 2 秒精度以内或因夏令时相差一小时的时间视为相同",
+		"since":              "只解压相对于此旧版归档新增或更改（按名称和 CRC 判断）的条目",
+		"small-first":        "先解压较小的条目",
+		"checkpoint":         "在此文件中记录已完成的条目，并跳过其中记录为已完成的条目（用于恢复中断的解压）",
+		"write-retries":      "系统报告文件忙（例如病毒扫描程序正在检查）时重试创建文件的次数",
+		"preallocate":        "写入前预留每个文件的完整大小，以减少碎片并在磁盘已满时尽早失败（仅 Linux、macOS、FreeBSD 和 Windows，其他系统忽略）",
+		"fsync":              "为防崩溃而执行 fsync 的对象：never、files（每个解压的文件）、dirs（有新条目的目录）或 all",
+		"zero-copy":          "直接从 ZIP 文件复制未压缩（stored）的条目；更快，但不校验其 CRC",
+		"threads":            "解码 Zstandard 压缩条目的线程数（0 表示 CPU 数）",
+		"warnings-as-errors": "如有任何警告（如无法转换的名称、可疑名称、跳过的条目或不合理的时间戳）则失败",
+		"keep-going":         "报告无法解压的条目并继续处理其余条目",
+		"entry-timeout":      "读取条目数据停滞达到此时长时放弃该条目（例如 30s；0 表示不超时）",
+		"route":              "按扩展名将文件放入子目录，例如 'jpg,png=images/;txt=docs/'",
+		"ascii-slugs":        "将输出名称音译为纯 ASCII 名称，并把对照表写入 " + slugsMapFilename,
+		"translit":           "-ascii-slugs 和 translit 使用的音译方案，按优先顺序排列（hepburn：日语假名，rr：韩语，iso9：西里尔字母，latin：变音符号，pinyin：汉字拼音，默认不使用）",
+		"fs-names":           "输出目录位于 FAT 或 NTFS 文件系统时：对其可能不接受的名称发出警告（warn）、修正（fix）或 off",
+		"preset":             "应用一组命名选项：" + presetNames() + "；显式指定的选项优先",
+		"fix-extensions":     "为缺少扩展名或扩展名不合理的文件追加检测到的内容类型的扩展名；要检测的类型用逗号分隔（例如 jpg,png,pdf），或 'all'",
+		"strip-controls":     "从名称中删除控制字符、双向覆盖字符和零宽字符",
+		"width-fold":         "将全角 ASCII 字母、数字和符号转换为 ASCII，并将半角片假名转换为全角",
+		"windows-names":      "即使不是解压到 Windows 或 FAT/NTFS 文件系统，也重命名 CON 或 NUL.txt 等 Windows 保留名称",
+		"transform-cmd":      "重命名或跳过条目的外部命令；它从标准输入逐条读取 JSON 请求，并按行写出 JSON 响应",
+		"wizard":             "交互式选择归档、代码页和目标位置，并预览名称",
+		"q":                  "不显示消息",
+		"lang":               "消息语言：en、ja、ko、zh 或 ru（默认取自 LANG）；用法、提问和常见消息会被翻译，其他错误仍为英文",
+		"explain-names":      "显示每个条目的输出名称是如何生成的：解码、转换命令、slug、清理和分类",
+		"export":             "不解压，而是将条目的名称、大小、日期、CRC 和编码写入此文件；CSV，若文件名以 .xlsx 结尾则为 XLSX",
+		"password-list":      "不解压，而是用此文件中的每个密码（每行一个）尝试加密条目，并报告哪些密码能打开它们",
+		"cache-dir":          "当归档以 http 或 https URL 给出时，将读取的部分按 URL 和 ETag 保存在此目录中，再次列出或解压时不再重新下载",
+		"list-cache":         "与 -l 一起使用时，按归档内容和选项缓存列表，下次直接显示缓存的列表",
+		"convert-content":    "将具有这些扩展名的文件内容从 -f 转换为 -t，例如 'txt,csv'（'*' 表示所有文件）",
+		"scan-text":          "解压后报告内容不是有效 UTF-8 或包含替换字符的文本文件",
+		"stats":              "解压后按压缩方法和名称编码显示统计信息",
+		"encoding-stats":     "在用户配置目录的 encoding-stats.json 中统计每个含非 UTF-8 名称的归档所用的代码页及其确定方式；不会发送到任何地方",
+		"names-map":          "写入 " + namesMapFilename + " 文件，记录每个已解压条目的原始名称、编码和输出路径",
+		"encrypt":            "add：使用 WinZip AES-256 加密新条目；已有条目按原样复制",
+		"password":           "add：-encrypt 使用的密码；系统的其他用户可能在进程列表中看到它",
+		"set-comment":        "comment：设置归档注释或指定条目的注释",
+		"transcode-comments": "comment：将归档和条目的注释从 -f 转换为 -t",
+		"census-format":      "census：报告的格式，csv 或 json",
+		"list-encodings":     "显示可用的代码页及其名称，然后退出",
+		"f":                  "ZIP 中文件名的代码页；'auto' 表示根据名称检测",
+		"t":                  "输出文件名的代码页。警告：除非完全清楚自己在做什么，否则不要更改！",
+	},
+	"ru": {
+		"l":                  "вывести имена файлов без распаковки",
+		"d":                  "каталог, в который распаковываются файлы",
+		"dest-per-archive":   "распаковывать каждый архив в каталог внутри -d, построенный по этому шаблону, например '{dir}/{base}'; {dir}, {base}, {name} и {ext} заменяются каталогом архива, именем без расширения, именем и расширением",
+		"marker":             "записывать каждый распакованный архив в файл " + markerFilename + " в каталоге назначения и пропускать архивы, записанные с тем же содержимым и параметрами",
+		"archives-from-0":    "также обработать архивы, перечисленные в этом файле через символ NUL, как выводит find -print0; '-' — стандартный ввод",
+		"manifest":           "также обработать архивы из CSV-отчёта census, каждый с указанной в нём кодовой страницей; исправьте столбец codepage или оставьте его пустым, чтобы использовать -f",
+		"jobs":               "обрабатывать столько архивов одновременно, каждый в отдельном процессе, чтобы сбой одного не затрагивал остальные; если -threads не задан, это же число потоков декодирования делится между ними",
+		"o":                  "перезаписывать существующие файлы",
+		"symlink-policy":     "как распаковывать символические ссылки: auto, link, junction (каталоги Windows), hardlink, copy, skip или file (файл с путём цели)",
+		"ads":                "как распаковывать альтернативные потоки данных NTFS с именами вида file.txt:stream: auto (потоки в Windows, отдельные файлы в других системах; только если сам файл тоже есть в архиве), stream, sidecar (файл с именем file.txt_stream) или skip",
+		"mac-forks":          "как распаковывать записи AppleDouble ._name с ресурсными ветвями Mac и данными Finder, обычно в __MACOSX: keep (с именами из архива), auto (восстановить в macOS, иначе отдельные файлы), restore (только macOS), sidecar (._name рядом с файлом) или skip",
+		"specials":           "как распаковывать записи FIFO, устройств и сокетов: skip, error или create",
+		"backup-existing":    "перемещать перезаписываемые файлы в каталог резервных копий с сохранением относительных путей; каталог задаётся как -backup-existing=DIR",
+		"k":                  "упорядоченно: создать подкаталог с именем ZIP-файла и поместить файлы в него",
+		"dir-data":           "что делать с записью, имя которой оканчивается косой чертой, но у которой есть данные: dir (игнорировать данные), file (записать как файл) или error",
+		"k-policy":           "что делать, если подкаталог -k существует и не пуст: merge, suffix или error",
+		"no-lock":            "не блокировать каталог назначения от других распаковок в него",
+		"wait":               "ждать завершения другой распаковки в тот же каталог вместо ошибки",
+		"staging":            "распаковывать во временный каталог и перемещать его на место только после завершения",
+		"sandbox":            "(только Linux) ограничить все записи при распаковке каталогом назначения с помощью openat2() и отклонять записи устройств, FIFO и setuid; не вместе с -staging",
+		"max-entries":        "отклонять архивы, в которых записей больше этого числа (0 — без ограничения)",
+		"max-depth":          "отклонять записи с большим числом уровней пути (0 — без ограничения)",
+		"read-order":         "порядок распаковки записей: cd (как в центральном каталоге) или offset (как хранятся в файле, для последовательного чтения)",
+		"source-tz":          "часовой пояс, в котором создан архив, например Asia/Tokyo, для времени изменения записей, у которых есть только метка времени DOS (по умолчанию местный)",
+		"dirs-only":          "создать только структуру каталогов, без файлов",
+		"update":             "распаковывать только отсутствующие файлы и файлы старше своих записей, заменяя старые; время в пределах 2-секундной точности меток DOS или различающееся на час из-за летнего времени считается одинаковым",
+		"since":              "распаковывать только записи, новые или изменённые (по имени и CRC) по сравнению с этой старой версией архива",
+		"small-first":        "распаковывать сначала меньшие записи",
+		"checkpoint":         "записывать завершённые записи в этот файл и пропускать отмеченные в нём как завершённые (для продолжения прерванной распаковки)",
+		"write-retries":      "сколько раз повторять создание файла, если система сообщает, что он занят, например при проверке антивирусом",
+		"preallocate":        "резервировать полный размер каждого файла перед записью, чтобы уменьшить фрагментацию и сразу сообщить о нехватке места (Linux, macOS, FreeBSD и Windows; в других системах игнорируется)",
+		"fsync":              "что синхронизировать через fsync для устойчивости к сбоям: never, files (каждый распакованный файл), dirs (каталоги с новыми записями) или all",
+		"zero-copy":          "копировать несжатые (stored) записи прямо из ZIP-файла; быстрее, но их CRC не проверяется",
+		"threads":            "число потоков для декодирования записи, сжатой Zstandard (0 — по числу процессоров)",
+		"warnings-as-errors": "завершаться ошибкой при любых предупреждениях, например о непреобразуемых или подозрительных именах, пропущенных записях или неправдоподобных метках времени",
+		"keep-going":         "сообщать о записях, которые не удаётся распаковать, и продолжать с остальными",
+		"entry-timeout":      "отказаться от записи, если чтение её данных стоит столько времени (например 30s; 0 — без тайм-аута)",
+		"route":              "раскладывать файлы по подкаталогам по расширению, например 'jpg,png=images/;txt=docs/'",
+		"ascii-slugs":        "транслитерировать выходные имена в ASCII и записать соответствие в " + slugsMapFilename,
+		"translit":           "схемы транслитерации для -ascii-slugs и translit в порядке предпочтения (hepburn: японская кана, rr: корейский, iso9: кириллица, latin: диакритика, pinyin: китайские иероглифы; не по умолчанию)",
+		"fs-names":           "если каталог назначения на файловой системе FAT или NTFS: предупреждать об именах, которые она может не принять (warn), исправлять их (fix) или off",
+		"preset":             "применить набор параметров именования: " + presetNames() + "; явно заданные параметры имеют приоритет",
+		"fix-extensions":     "добавлять расширение обнаруженного типа содержимого к файлам без расширения или с неправдоподобным расширением; список типов через запятую (например jpg,png,pdf) или 'all'",
+		"strip-controls":     "удалять из имён управляющие символы, переопределения направления и символы нулевой ширины",
+		"width-fold":         "преобразовывать полноширинные латинские буквы, цифры и символы в ASCII, а полуширинную катакану — в полноширинную",
+		"windows-names":      "переименовывать зарезервированные имена Windows, такие как CON или NUL.txt, даже при распаковке не в Windows и не на FAT/NTFS",
+		"transform-cmd":      "внешняя команда, которая переименовывает или пропускает записи; она читает JSON-запрос на каждую запись из стандартного ввода и пишет JSON-ответ построчно",
+		"wizard":             "выбрать архив, кодовую страницу и каталог назначения интерактивно, с предпросмотром имён",
+		"q":                  "не выводить сообщения",
+		"lang":               "язык сообщений: en, ja, ko, zh или ru (по умолчанию из LANG); переводятся справка, вопросы и основные сообщения, остальные ошибки остаются на английском",
+		"explain-names":      "показать, как получено выходное имя каждой записи: декодирование, внешняя команда, транслитерация, очистка и раскладка",
+		"export":             "вместо распаковки записать имена, размеры, даты, CRC и кодировки записей в этот файл; CSV или XLSX, если имя оканчивается на .xlsx",
+		"password-list":      "вместо распаковки проверить каждый пароль из этого файла (по одному в строке) на зашифрованных записях и сообщить, какие из них подходят",
+		"cache-dir":          "если архив задан URL http или https, сохранять прочитанные части в этом каталоге по URL и ETag, чтобы при повторном просмотре или распаковке не скачивать их снова",
+		"list-cache":         "с -l кэшировать список по содержимому архива и параметрам и в следующий раз выводить кэшированный список",
+		"convert-content":    "преобразовывать содержимое файлов с этими расширениями из -f в -t, например 'txt,csv' ('*' — все файлы)",
+		"scan-text":          "после распаковки сообщить о текстовых файлах, содержимое которых не является корректным UTF-8 или содержит символы замены",
+		"stats":              "после распаковки вывести статистику по методам сжатия и кодировкам имён",
+		"encoding-stats":     "подсчитывать в encoding-stats.json в каталоге настроек пользователя кодовую страницу каждого архива с именами не в UTF-8 и то, как она выбрана; ничего никуда не отправляется",
+		"names-map":          "записать файл " + namesMapFilename + " с исходным именем, кодировкой и выходным путём каждой распакованной записи",
+		"encrypt":            "add: шифровать новые записи WinZip AES-256; существующие записи копируются как есть",
+		"password":           "add: пароль для -encrypt; другие пользователи системы могут увидеть его в списке процессов",
+		"set-comment":        "comment: задать комментарий архива или указанной записи",
+		"transcode-comments": "comment: преобразовать комментарии архива и записей из -f в -t",
+		"census-format":      "census: формат отчёта, csv или json",
+		"list-encodings":     "вывести доступные кодовые страницы и их имена и выйти",
+		"f":                  "кодовая страница имён файлов в ZIP; 'auto' — определить по именам",
+		"t":                  "кодовая страница выходных имён файлов. ВНИМАНИЕ: меняйте, только если точно знаете, что делаете!",
+	},
+}
+
+// translate the description of a flag into the language of messages
+func trFlag(name, usage string) string {
+	if t, ok := flagCatalogs[messageLang()][name]; ok {
+		return t
+	}
+	return usage
+}
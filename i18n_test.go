@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func TestFlagCatalogs(t *testing.T) {
+	var list bool
+	specs := flagSpecs(&list)
+	names := map[string]bool{}
+	for _, s := range specs {
+		names[s.name] = true
+	}
+	for lang, catalog := range flagCatalogs {
+		for _, s := range specs {
+			if catalog[s.name] == "" {
+				t.Errorf("%s: no translation of -%s", lang, s.name)
+			}
+		}
+		for name := range catalog {
+			if !names[name] {
+				t.Errorf("%s: translation of an unknown flag -%s", lang, name)
+			}
+		}
+	}
+}
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"Usage:", 6},
+		{"使い方:", 7},
+		{"사용법:", 7},
+		{"Использование:", 14},
+	}
+	for _, tt := range tests {
+		if got := displayWidth(tt.s); got != tt.want {
+			t.Errorf("displayWidth(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestMessageLang(t *testing.T) {
+	defer func(args []string) { os.Args = args }(os.Args)
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "ko_KR.UTF-8")
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "ko"},
+		{[]string{"-lang=ja"}, "ja"},
+		{[]string{"--lang=ja"}, "ja"},
+		{[]string{"-lang", "zh"}, "zh"},
+		{[]string{"--lang", "ru_RU"}, "ru"},
+		{[]string{"lang=ja"}, "ko"}, // an archive name, not a flag
+	}
+	for _, tt := range tests {
+		os.Args = append([]string{"codepage-unzip"}, tt.args...)
+		if got := messageLang(); got != tt.want {
+			t.Errorf("messageLang() with %q = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+// every language translates the same messages, with the same verbs
+func TestCatalogs(t *testing.T) {
+	verbs := regexp.MustCompile(`%[a-z]`)
+	for _, catalog := range catalogs {
+		for _, other := range catalogs {
+			for msg := range other {
+				if _, ok := catalog[msg]; !ok {
+					t.Errorf("%q is not translated in every language", msg)
+				}
+			}
+		}
+	}
+	for lang, catalog := range catalogs {
+		for msg, s := range catalog {
+			if want, got := verbs.FindAllString(msg, -1), verbs.FindAllString(s, -1); !slices.Equal(want, got) {
+				t.Errorf("%s: %q has the verbs %v, want %v", lang, s, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,478 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/mixcode/codepage-unzip/codepagezip"
+)
+
+const (
+	isoSectorSize       = 2048
+	isoDescriptorSector = 16       // the first volume descriptor
+	maxISODirSize       = 64 << 20 // a larger directory is broken or crafted
+)
+
+// names in ISO9660 images, in the order of preference
+const (
+	ISONamesJoliet    = "joliet"    // UCS-2 names of the Joliet supplementary volume descriptor
+	ISONamesRockRidge = "rockridge" // NM entries of Rock Ridge, raw bytes in the codepage of the system that made the image
+	ISONamesPlain     = "iso9660"   // the names of the primary volume descriptor, often in a codepage despite the standard
+)
+
+// an entry of an ISO9660 image
+type isoEntry struct {
+	raw     string     // the name as recorded: UTF-8 for Joliet, raw bytes otherwise
+	name    string     // the converted path, slash-separated
+	from    string     // the codepage the name was converted from
+	dir     bool       // a directory
+	link    bool       // a Rock Ridge symbolic link
+	extents [][2]int64 // offsets and lengths of the data; files over 4 GiB have several
+	modTime time.Time
+}
+
+// an ISO9660 image being read
+type isoImage struct {
+	f            *os.File
+	blockSize    int64
+	joliet       bool
+	hasRockRidge bool
+	visited      map[int64]bool // directory extents, against loops
+	entries      []*isoEntry
+}
+
+// whether a file is an ISO9660 image, by the standard identifier of the first volume descriptor
+func isISOImage(f io.ReaderAt) bool {
+	id := make([]byte, 5)
+	_, err := f.ReadAt(id, isoDescriptorSector*isoSectorSize+1)
+	return err == nil && string(id) == "CD001"
+}
+
+// read the volume descriptors, and the directory tree of the Joliet or the primary volume descriptor
+func readISOImage(f *os.File) (img *isoImage, err error) {
+	img = &isoImage{f: f, visited: make(map[int64]bool)}
+	var primary, joliet []byte
+	for sector := int64(isoDescriptorSector); ; sector++ {
+		vd := make([]byte, isoSectorSize)
+		_, err = f.ReadAt(vd, sector*isoSectorSize)
+		if err != nil {
+			return nil, fmt.Errorf("reading the volume descriptors: %w", err)
+		}
+		if string(vd[1:6]) != "CD001" {
+			return nil, fmt.Errorf("broken volume descriptor at sector %d", sector)
+		}
+		switch vd[0] {
+		case 1:
+			if primary == nil {
+				primary = vd
+			}
+		case 2:
+			// Joliet is marked by the escape sequence of UCS-2 level 1, 2 or 3
+			if esc := vd[88:91]; esc[0] == '%' && esc[1] == '/' && (esc[2] == '@' || esc[2] == 'C' || esc[2] == 'E') {
+				joliet = vd
+			}
+		}
+		if vd[0] == 255 {
+			break
+		}
+	}
+	if primary == nil {
+		return nil, fmt.Errorf("no primary volume descriptor")
+	}
+
+	vd := primary
+	if joliet != nil {
+		vd = joliet
+		img.joliet = true
+	}
+	img.blockSize = int64(binary.LittleEndian.Uint16(vd[128:]))
+	if img.blockSize == 0 {
+		img.blockSize = isoSectorSize
+	}
+	root := vd[156 : 156+34]
+	err = img.readDir(int64(binary.LittleEndian.Uint32(root[2:])), int64(binary.LittleEndian.Uint32(root[10:])), "", 0)
+	if err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// the names in use
+func (img *isoImage) names() string {
+	switch {
+	case img.joliet:
+		return ISONamesJoliet
+	case img.hasRockRidge:
+		return ISONamesRockRidge
+	}
+	return ISONamesPlain
+}
+
+// read the records of a directory extent, and the directories under it
+func (img *isoImage) readDir(lba, size int64, parent string, depth int) error {
+	if img.visited[lba] {
+		return fmt.Errorf("%s: the directory tree has a loop", parent)
+	}
+	img.visited[lba] = true
+	if maxDepth > 0 && depth >= maxDepth {
+		return fmt.Errorf("%s is %d levels deep, which exceeds the limit of %d (see -max-depth)", parent, depth, maxDepth)
+	}
+	if size > maxISODirSize {
+		return fmt.Errorf("the directory %s is too large: %d bytes", parent, size)
+	}
+	data := make([]byte, size)
+	_, err := img.f.ReadAt(data, lba*img.blockSize)
+	if err != nil {
+		return fmt.Errorf("reading the directory %s: %w", parent, err)
+	}
+
+	var subdirs []*isoEntry
+	var last *isoEntry // a file continued in the next record
+	lastName := ""
+	for off := 0; off < len(data); {
+		n := int(data[off])
+		if n == 0 {
+			// records do not cross sectors; the rest of the sector is padding
+			off = (off/isoSectorSize + 1) * isoSectorSize
+			continue
+		}
+		if n < 34 || off+n > len(data) {
+			return fmt.Errorf("broken directory record in %s", parent)
+		}
+		rec := data[off : off+n]
+		off += n
+
+		nameLen := int(rec[32])
+		if 33+nameLen > n {
+			return fmt.Errorf("broken directory record in %s", parent)
+		}
+		id := rec[33 : 33+nameLen]
+		if nameLen == 1 && (id[0] == 0 || id[0] == 1) {
+			continue // . and ..
+		}
+		flags := rec[25]
+		extent := [2]int64{int64(binary.LittleEndian.Uint32(rec[2:])) * img.blockSize, int64(binary.LittleEndian.Uint32(rec[10:]))}
+
+		name, link := img.recordName(id, rec[min(33+nameLen+(1-nameLen%2), n):])
+		if last != nil && lastName == name {
+			last.extents = append(last.extents, extent)
+		} else {
+			e := &isoEntry{raw: name, dir: flags&0x02 != 0, link: link, extents: [][2]int64{extent}, modTime: isoTime(rec[18:25])}
+			if parent != "" {
+				e.raw = parent + "/" + name
+			}
+			img.entries = append(img.entries, e)
+			if maxEntries > 0 && len(img.entries) > maxEntries {
+				return fmt.Errorf("the image has more than %d entries (see -max-entries)", maxEntries)
+			}
+			if e.dir && !link {
+				subdirs = append(subdirs, e)
+			}
+			last, lastName = e, name
+		}
+		if flags&0x80 == 0 {
+			last = nil // the last extent of the file
+		}
+	}
+
+	for _, d := range subdirs {
+		err = img.readDir(d.extents[0][0]/img.blockSize, d.extents[0][1], d.raw, depth+1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// the name of a directory record: the Joliet name in UTF-8, the Rock Ridge name, or the ISO9660 name without its version.
+// link reports a Rock Ridge symbolic link.
+func (img *isoImage) recordName(id, systemUse []byte) (name string, link bool) {
+	if img.joliet {
+		u := make([]uint16, len(id)/2)
+		for i := range u {
+			u[i] = binary.BigEndian.Uint16(id[2*i:])
+		}
+		name = string(utf16.Decode(u))
+	} else if nm, link, ok := img.rockRidge(systemUse); ok {
+		img.hasRockRidge = true
+		return nm, link
+	} else {
+		name = string(id)
+	}
+	if i := strings.LastIndexByte(name, ';'); i >= 0 {
+		name = name[:i]
+	}
+	if strings.HasSuffix(name, ".") && !strings.HasSuffix(name, "..") {
+		name = strings.TrimSuffix(name, ".") // a name without an extension
+	}
+	return strings.ReplaceAll(name, "/", "_"), false
+}
+
+// the name in the NM entries of the System Use Sharing Protocol area of a record, following CE continuations,
+// and whether an SL entry makes it a symbolic link
+func (img *isoImage) rockRidge(su []byte) (name string, link, ok bool) {
+	var nm []byte
+	for hops := 0; hops < 16; hops++ {
+		var next []byte
+		for len(su) >= 4 {
+			sig, n := string(su[:2]), int(su[2])
+			if n < 4 || n > len(su) {
+				break
+			}
+			switch sig {
+			case "NM":
+				if n >= 5 && su[4]&0x06 == 0 { // not the current or the parent directory
+					nm = append(nm, su[5:n]...)
+				}
+			case "SL":
+				link = true
+			case "CE":
+				if n >= 28 {
+					block := int64(binary.LittleEndian.Uint32(su[4:]))
+					offset := int64(binary.LittleEndian.Uint32(su[12:]))
+					length := int64(binary.LittleEndian.Uint32(su[20:]))
+					if length <= isoSectorSize {
+						next = make([]byte, length)
+						if _, err := img.f.ReadAt(next, block*img.blockSize+offset); err != nil {
+							next = nil
+						}
+					}
+				}
+			}
+			if sig == "ST" {
+				break // the end of the area
+			}
+			su = su[n:]
+		}
+		if next == nil {
+			break
+		}
+		su = next
+	}
+	return strings.ReplaceAll(string(nm), "/", "_"), link, len(nm) > 0
+}
+
+// the time of a directory record: years since 1900, month, day, hour, minute, second, and the offset from GMT in 15 minutes
+func isoTime(b []byte) time.Time {
+	if b[0] == 0 && b[1] == 0 {
+		return time.Time{}
+	}
+	zone := time.FixedZone("", int(int8(b[6]))*15*60)
+	return time.Date(1900+int(b[0]), time.Month(b[1]), int(b[2]), int(b[3]), int(b[4]), int(b[5]), 0, zone)
+}
+
+// convert the names of the entries of an image; Joliet names are already Unicode
+func (img *isoImage) convertNames() error {
+	if wantsDetection() && !img.joliet {
+		var raw []string
+		for _, e := range img.entries {
+			if !utf8.ValidString(e.raw) {
+				raw = append(raw, e.raw)
+			}
+		}
+		defer useDetection(detectNames(raw))()
+	}
+	for _, e := range img.entries {
+		from := convertFrom
+		if img.joliet || utf8.ValidString(e.raw) {
+			from = UTF8
+		}
+		name, err := codepagezip.ConvertString(e.raw, from, convertTo)
+		if err != nil {
+			return fmt.Errorf("%q: converting from %s to %s: %w", e.raw, from, convertTo, err)
+		}
+		e.name, e.from = name, from
+	}
+	return nil
+}
+
+// list or extract an ISO9660 image
+func runISO(filename string) (err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	img, err := readISOImage(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	err = img.convertNames()
+	if err != nil {
+		return
+	}
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "Reading the %s names of the image\n", img.names())
+	}
+
+	if cmd == CmdList {
+		for _, e := range img.entries {
+			if e.dir {
+				fmt.Printf("%s/\n", e.name)
+			} else {
+				fmt.Printf("%s\n", e.name)
+			}
+		}
+		return nil
+	}
+
+	if keepFileDir {
+		destDir, err = keepDirPath(filepath.Join(destDir, keepDirName(filename)))
+		if err != nil {
+			return
+		}
+	}
+
+	// names the destination filesystem may not accept, as for zip archives
+	var fsc fsConstraint
+	if fsNames != FsNamesOff {
+		fsc = destConstraint(destDir)
+		if fsc.asciiOnly && fsNames == FsNamesFix && !asciiSlugs {
+			if !quiet {
+				fmt.Printf("The output directory is on a %s filesystem without unicode names; using -ascii-slugs\n", fsc.fsType)
+			}
+			asciiSlugs = true
+		}
+	}
+	var slugs *slugger
+	if asciiSlugs {
+		slugs = newSlugger()
+	}
+	names := make([]string, len(img.entries))
+	for i, e := range img.entries {
+		name := e.name
+		if e.dir {
+			name += "/"
+		}
+		if slugs != nil {
+			name = slugs.name(name)
+		}
+		if fsc.windowsSafe && fsNames == FsNamesFix {
+			name = windowsSafePath(name)
+		}
+		if fixed := limitPath(name, fsc.needWindowsNames()); fixed != name {
+			if !quiet {
+				fmt.Fprintf(os.Stderr, "Renamed '%s' to '%s'\n", name, fixed)
+			}
+			name = fixed
+		}
+		names[i] = name
+	}
+	if fsNames == FsNamesWarn && (fsc.windowsSafe || fsc.asciiOnly) {
+		warnFsNames(fsc, names)
+	}
+
+	finish, err := openOutput(filename, slugs)
+	if err != nil {
+		return
+	}
+	defer finish(&err)
+
+	var dirs []*isoEntry
+	var dirPaths []string
+	for i, e := range img.entries {
+		rel := codepagezip.SanitizePath(names[i])
+		if rel == "" {
+			continue
+		}
+		outpath := filepath.Join(destDir, filepath.FromSlash(rel))
+		err = checkDirBeneath(destDir, filepath.Dir(outpath))
+		if err != nil {
+			return
+		}
+		if e.link {
+			if !quiet {
+				fmt.Printf("skipping symlink %s\n", e.name)
+			}
+			continue
+		}
+		if dirsOnly && !e.dir {
+			// only the parent directory of a file
+			err = makeDir(filepath.Dir(outpath))
+			if err != nil {
+				return
+			}
+			continue
+		}
+		if !quiet {
+			fmt.Printf("%s\n", strings.TrimSuffix(names[i], "/"))
+		}
+		if e.dir {
+			err = makeDir(outpath)
+			if err != nil {
+				return
+			}
+			dirs = append(dirs, e)
+			dirPaths = append(dirPaths, outpath)
+			continue
+		}
+		err = writeISOFile(f, e, names[i], outpath)
+		if err != nil {
+			return fmt.Errorf("%s: %w", e.name, err)
+		}
+		if nameMap != nil {
+			err = nameMap.add(e.raw, e.from, outpath)
+			if err != nil {
+				return
+			}
+		}
+	}
+	// the times of directories, after their contents are written
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if t := dirs[i].modTime; !t.IsZero() {
+			setFileTimes(dirPaths[i], t, t)
+		}
+	}
+	return nil
+}
+
+// write the data of a file of an image
+func writeISOFile(f *os.File, e *isoEntry, name, outpath string) (err error) {
+	existing := outpath
+	if stagingFinal != "" {
+		existing = finalPath(outpath)
+	}
+	if st, e2 := os.Lstat(existing); e2 == nil {
+		if st.IsDir() {
+			return fmt.Errorf("cannot create file %s", name)
+		}
+		if !overwrite {
+			fmt.Printf(tr("The output file '%s' already exists."), name)
+			if !promptYN(tr(" Overwrite? (y/N)"), false) {
+				return nil
+			}
+		}
+	}
+	err = makeDir(filepath.Dir(outpath))
+	if err != nil {
+		return
+	}
+	fo, err := createFile(outpath)
+	if err != nil {
+		return
+	}
+	for _, x := range e.extents {
+		_, err = io.CopyN(fo, io.NewSectionReader(f, x[0], x[1]), x[1])
+		if err != nil {
+			break
+		}
+	}
+	if e2 := fo.Close(); err == nil {
+		err = e2
+	}
+	if err != nil {
+		removeFile(outpath)
+		return
+	}
+	if !e.modTime.IsZero() {
+		err = setFileTimes(outpath, e.modTime, e.modTime)
+	}
+	return
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+// a directory record pointing at an extent, with a System Use area
+func isoRecord(id []byte, lba, size uint32, dir bool, systemUse []byte) []byte {
+	n := 33 + len(id)
+	if n%2 == 1 {
+		n++
+	}
+	rec := make([]byte, n, n+len(systemUse))
+	rec = append(rec, systemUse...)
+	rec[0] = byte(len(rec))
+	binary.LittleEndian.PutUint32(rec[2:], lba)
+	binary.BigEndian.PutUint32(rec[6:], lba)
+	binary.LittleEndian.PutUint32(rec[10:], size)
+	binary.BigEndian.PutUint32(rec[14:], size)
+	copy(rec[18:25], []byte{126, 1, 2, 3, 4, 5, 36}) // 2026-01-02 03:04:05 +09:00
+	if dir {
+		rec[25] = 0x02
+	}
+	rec[32] = byte(len(id))
+	copy(rec[33:], id)
+	return rec
+}
+
+// a Rock Ridge NM entry
+func isoNM(name string) []byte {
+	return append([]byte{'N', 'M', byte(5 + len(name)), 1, 0}, name...)
+}
+
+func ucs2(s string) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = binary.BigEndian.AppendUint16(b, u)
+	}
+	return b
+}
+
+// an image with a directory holding a file, named in the ISO9660 identifiers, Rock Ridge or Joliet
+func writeISOImage(t *testing.T, dirID, fileID []byte, dirSU, fileSU []byte, joliet bool) string {
+	t.Helper()
+	const rootLBA, dirLBA, dataLBA = 20, 21, 22
+	img := make([]byte, (dataLBA+1)*isoSectorSize)
+	sector := func(n int) []byte { return img[n*isoSectorSize : (n+1)*isoSectorSize] }
+
+	dot := func(lba uint32, id byte) []byte { return isoRecord([]byte{id}, lba, isoSectorSize, true, nil) }
+	root := sector(rootLBA)
+	off := 0
+	for _, rec := range [][]byte{dot(rootLBA, 0), dot(rootLBA, 1), isoRecord(dirID, dirLBA, isoSectorSize, true, dirSU)} {
+		off += copy(root[off:], rec)
+	}
+	dir := sector(dirLBA)
+	off = 0
+	for _, rec := range [][]byte{dot(dirLBA, 0), dot(rootLBA, 1), isoRecord(fileID, dataLBA, 5, false, fileSU)} {
+		off += copy(dir[off:], rec)
+	}
+	copy(sector(dataLBA), "hello")
+
+	descriptor := func(n int, kind byte) []byte {
+		vd := sector(n)
+		vd[0] = kind
+		copy(vd[1:], "CD001")
+		vd[6] = 1
+		binary.LittleEndian.PutUint16(vd[128:], isoSectorSize)
+		copy(vd[156:], dot(rootLBA, 0))
+		return vd
+	}
+	descriptor(16, 1)
+	if joliet {
+		copy(descriptor(17, 2)[88:], "%/E")
+		descriptor(18, 255)
+	} else {
+		descriptor(17, 255)
+	}
+
+	filename := filepath.Join(t.TempDir(), "t.iso")
+	if err := os.WriteFile(filename, img, 0666); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestISOImage(t *testing.T) {
+	defer func(from string) { convertFrom = from }(convertFrom)
+	convertFrom = "CP932"
+	sjisDir, sjisFile := []byte("\x83e\x83X\x83g"), []byte("\x93\xfa\x96{\x8c\xea.txt")
+
+	tests := []struct {
+		name           string
+		dirID, fileID  []byte
+		dirSU, fileSU  []byte
+		joliet         bool
+		wantNames      string
+		wantDir, wantF string
+	}{
+		{"plain", sjisDir, append(sjisFile, ";1"...), nil, nil, false, ISONamesPlain, "テスト", "テスト/日本語.txt"},
+		{"plain without an extension", []byte("DIR"), []byte("README.;1"), nil, nil, false, ISONamesPlain, "DIR", "DIR/README"},
+		{"rock ridge", []byte("TEST"), []byte("NIHONGO.TXT;1"), isoNM(string(sjisDir)), isoNM(string(sjisFile)), false, ISONamesRockRidge, "テスト", "テスト/日本語.txt"},
+		{"joliet", ucs2("テスト"), ucs2("日本語.txt;1"), nil, nil, true, ISONamesJoliet, "テスト", "テスト/日本語.txt"},
+		{"joliet with a slash", ucs2("a/b"), ucs2("c.txt;1"), nil, nil, true, ISONamesJoliet, "a_b", "a_b/c.txt"},
+	}
+	for _, tt := range tests {
+		f, err := os.Open(writeISOImage(t, tt.dirID, tt.fileID, tt.dirSU, tt.fileSU, tt.joliet))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if !isISOImage(f) {
+			t.Fatalf("%s: not an ISO9660 image", tt.name)
+		}
+		img, err := readISOImage(f)
+		if err == nil {
+			err = img.convertNames()
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if img.names() != tt.wantNames {
+			t.Errorf("%s: names from %s, want %s", tt.name, img.names(), tt.wantNames)
+		}
+		var got []string
+		for _, e := range img.entries {
+			got = append(got, e.name)
+		}
+		if want := []string{tt.wantDir, tt.wantF}; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: %q, want %q", tt.name, got, want)
+			continue
+		}
+		e := img.entries[1]
+		if e.dir || !reflect.DeepEqual(e.extents, [][2]int64{{22 * isoSectorSize, 5}}) {
+			t.Errorf("%s: %s is a directory %v with the extents %v", tt.name, e.name, e.dir, e.extents)
+		}
+		if want := time.Date(2026, 1, 2, 3, 4, 5, 0, time.FixedZone("", 9*3600)); !e.modTime.Equal(want) {
+			t.Errorf("%s: modified at %v, want %v", tt.name, e.modTime, want)
+		}
+	}
+}
+
+// the limits apply to images, and a directory that contains itself is refused
+func TestISOImageLimits(t *testing.T) {
+	defer func(e, d int) { maxEntries, maxDepth = e, d }(maxEntries, maxDepth)
+	filename := writeISOImage(t, []byte("DIR"), []byte("F.TXT;1"), nil, nil, false)
+	tests := []struct {
+		name                 string
+		maxEntries, maxDepth int
+		fails                bool
+	}{
+		{"no limits", 0, 0, false},
+		{"within the limits", 2, 2, false},
+		{"too many entries", 1, 0, true},
+		{"too deep", 0, 1, true},
+	}
+	for _, tt := range tests {
+		maxEntries, maxDepth = tt.maxEntries, tt.maxDepth
+		f, err := os.Open(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = readISOImage(f)
+		f.Close()
+		if (err != nil) != tt.fails {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+	}
+
+	// DIR pointing back at the root
+	maxEntries, maxDepth = 0, 0
+	img, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(img[20*isoSectorSize+68:], isoRecord([]byte("DIR"), 20, isoSectorSize, true, nil))
+	if err = os.WriteFile(filename, img, 0666); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err = readISOImage(f); err == nil || !strings.Contains(err.Error(), "loop") {
+		t.Errorf("a directory loop: %v", err)
+	}
+}
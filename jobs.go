@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+)
+
+// archives processed at once in batch mode, each in a process of its own; it is also the budget
+// of threads they share for decoding entries, unless -threads is given
+var batchJobs = 1
+
+// flags choosing the archives of a batch and their output directories, which are not passed on to the
+// process of each archive; it gets its own -d, -f and -threads
+var batchFlags = map[string]bool{
+	"archives-from-0": true, "manifest": true, "dest-per-archive": true, "jobs": true,
+	"d": true, "f": true, "threads": true,
+}
+
+// runs the archives of a batch in processes of their own, so that a corrupt archive, or a crash,
+// affects only its own, and their global state is not shared
+type jobPool struct {
+	exe     string
+	threads int // -threads for each process
+	slots   chan struct{}
+	wg      sync.WaitGroup
+	mu      sync.Mutex // for printing the output of a process at once, and for failed
+	failed  int
+}
+
+func newJobPool(archives int) (*jobPool, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	jobs := min(batchJobs, archives)
+	threads := decodeThreads
+	if !flagGiven("threads") {
+		threads = max(1, batchJobs/jobs)
+	}
+	return &jobPool{exe: exe, threads: threads, slots: make(chan struct{}, jobs)}, nil
+}
+
+// the arguments for processing an archive with the current options, as run() in the batch would
+func (p *jobPool) args(zipname string) []string {
+	var args []string
+	flag.Visit(func(f *flag.Flag) {
+		if !batchFlags[f.Name] {
+			args = append(args, "-"+f.Name+"="+f.Value.String())
+		}
+	})
+	args = append(args, "-d="+destDir, "-f="+convertFrom, "-threads="+strconv.Itoa(p.threads))
+	if !noLock {
+		args = append(args, "-wait") // for archives extracted into the same directory
+	}
+	return append(args, "--", zipname)
+}
+
+// start processing an archive when a slot is free, and print its output when it is done
+func (p *jobPool) start(zipname string) {
+	p.slots <- struct{}{}
+	c := exec.Command(p.exe, p.args(zipname)...)
+	var stdout, stderr bytes.Buffer
+	c.Stdout, c.Stderr = &stdout, &stderr
+	p.wg.Add(1)
+	go func() {
+		defer func() { <-p.slots; p.wg.Done() }()
+		err := c.Run()
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if !quiet && !useMarkers { // with -marker, the process prints it
+			fmt.Printf("Archive: %s\n", zipname)
+		}
+		os.Stdout.Write(stdout.Bytes())
+		os.Stderr.Write(stderr.Bytes())
+		if err != nil {
+			p.failed++
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) {
+				fmt.Fprintf(os.Stderr, "Error: %s: %v\n", zipname, err)
+			} else if quiet { // the error printed by the process does not tell the archive
+				fmt.Fprintf(os.Stderr, "Error: %s failed\n", zipname)
+			}
+		}
+	}()
+}
+
+// wait for all the archives to be done, and return the number that failed
+func (p *jobPool) wait() int {
+	p.wg.Wait()
+	return p.failed
+}
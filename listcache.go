@@ -0,0 +1,112 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+var listCache = false // cache listings by the archive contents and options
+
+// the cache file of the listing of an archive, keyed by its size, modification time and central directory, and the options
+func listCacheFile(zipname string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	st, err := os.Stat(zipname)
+	if err != nil {
+		return "", err
+	}
+	sum, err := centralDirectorySHA256(zipname, st.Size())
+	if err != nil {
+		return "", err
+	}
+	key := sha256.Sum256([]byte(fmt.Sprintf("%d\n%d\n%s\n%s", st.Size(), st.ModTime().UnixNano(), sum, outputOptions())))
+	return filepath.Join(dir, "codepage-unzip", "list-"+hex.EncodeToString(key[:16])), nil
+}
+
+// the hash of the central directory and the end of central directory records of a ZIP file,
+// which hold everything a listing shows; reading them is cheap unlike hashing the whole archive
+func centralDirectorySHA256(zipname string, size int64) (string, error) {
+	f, err := os.Open(zipname)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	// the end of central directory record is in the last 22 bytes and a comment of up to 65535 bytes
+	tailLen := int64(22 + 65535 + 20)
+	if tailLen > size {
+		tailLen = size
+	}
+	tail := make([]byte, tailLen)
+	_, err = f.ReadAt(tail, size-tailLen)
+	if err != nil {
+		return "", err
+	}
+	// the last signature whose record and comment fit in the file; the comment may contain the signature
+	i := len(tail)
+	for {
+		i = bytes.LastIndex(tail[:i], []byte("PK\x05\x06"))
+		if i < 0 {
+			return "", zip.ErrFormat
+		}
+		if len(tail)-i >= 22 && 22+int(binary.LittleEndian.Uint16(tail[i+20:])) <= len(tail)-i {
+			break
+		}
+	}
+	eocd := size - tailLen + int64(i)
+	cdSize := int64(binary.LittleEndian.Uint32(tail[i+12:]))
+	cdEnd := eocd
+	if cdSize == 0xffffffff && i >= 20 && bytes.HasPrefix(tail[i-20:], []byte("PK\x06\x07")) {
+		// ZIP64: the locator before the record points to the ZIP64 end of central directory record
+		rec := make([]byte, 56)
+		cdEnd = int64(binary.LittleEndian.Uint64(tail[i-20+8:]))
+		if _, err = f.ReadAt(rec, cdEnd); err != nil {
+			return "", err
+		}
+		cdSize = int64(binary.LittleEndian.Uint64(rec[40:]))
+	}
+	// the central directory ends where the end records begin, even with data prepended to the archive
+	if cdSize > cdEnd {
+		return "", zip.ErrFormat
+	}
+	h := sha256.New()
+	_, err = io.Copy(h, io.NewSectionReader(f, cdEnd-cdSize, size-(cdEnd-cdSize)))
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// print a cached listing if there is one
+func printCachedList(filename string) bool {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return false
+	}
+	os.Stdout.Write(data)
+	return true
+}
+
+// save a listing in the cache
+func saveListCache(filename string, listing *bytes.Buffer) error {
+	err := os.MkdirAll(filepath.Dir(filename), fs.ModePerm)
+	if err != nil {
+		return err
+	}
+	tmp := filename + ".tmp"
+	err = os.WriteFile(tmp, listing.Bytes(), 0666)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var (
+	noLock   = false // do not lock the output directory
+	waitLock = false // wait for another extraction into the output directory instead of failing
+)
+
+const lockPollInterval = 200 * time.Millisecond
+
+// the lock file of an output directory, in the user cache directory keyed by the hash of its absolute path,
+// so that no file is left in or beside the directory and the directory itself can be staged
+func lockPath(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		cache = os.TempDir()
+	}
+	key := sha256.Sum256([]byte(filepath.Clean(abs)))
+	return filepath.Join(cache, "codepage-unzip", "locks", hex.EncodeToString(key[:16])+".lock"), nil
+}
+
+// lock an output directory against concurrent extractions; call the returned function to unlock
+func lockDestination(dir string) (unlock func(), err error) {
+	path, err := lockPath(dir)
+	if err != nil {
+		return
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0777)
+	if err != nil {
+		return
+	}
+	waiting := false
+	for {
+		var ok bool
+		unlock, ok, err = tryLockFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("locking %s: %w (use -no-lock to extract without locking)", dir, err)
+		}
+		if ok {
+			return
+		}
+		if !waitLock {
+			return nil, fmt.Errorf(tr("another extraction into %s is running (use -wait to wait for it)"), dir)
+		}
+		if !waiting && !quiet {
+			fmt.Printf(tr("Waiting for another extraction into %s to finish\n"), dir)
+			waiting = true
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !unix && !windows
+
+package main
+
+// file locking is not available; extractions are not serialized
+func tryLockFile(path string) (unlock func(), ok bool, err error) {
+	return func() {}, true, nil
+}
@@ -0,0 +1,40 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// try to take an exclusive lock of a file without blocking; ok is false if it is held by another process.
+// The file is removed on unlock.
+func tryLockFile(path string) (unlock func(), ok bool, err error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return
+	}
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		f.Close()
+		return nil, false, nil
+	}
+	if err != nil {
+		f.Close()
+		return
+	}
+	// the holder before us may have removed the file after we opened it; then the lock is on a stale file
+	fst, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return
+	}
+	if st, e := os.Stat(path); e != nil || !os.SameFile(st, fst) {
+		f.Close()
+		return tryLockFile(path)
+	}
+	return func() {
+		os.Remove(path)
+		f.Close()
+	}, true, nil
+}
@@ -0,0 +1,22 @@
+package main
+
+import "syscall"
+
+// try to open a file exclusively; ok is false if it is open in another process.
+// The file is deleted when closed.
+func tryLockFile(path string) (unlock func(), ok bool, err error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return
+	}
+	const fileFlagDeleteOnClose = 0x04000000
+	h, err := syscall.CreateFile(p, syscall.GENERIC_READ|syscall.GENERIC_WRITE, 0, nil,
+		syscall.OPEN_ALWAYS, syscall.FILE_ATTRIBUTE_NORMAL|fileFlagDeleteOnClose, 0)
+	if err == errorSharingViolation {
+		return nil, false, nil
+	}
+	if err != nil {
+		return
+	}
+	return func() { syscall.CloseHandle(h) }, true, nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+)
+
+// how to extract AppleDouble entries, ._name files holding the resource fork and Finder info
+// of name, which macOS stores in archives under __MACOSX/
+const (
+	MacForksKeep    = "keep"    // extract them as they are named in the archive
+	MacForksAuto    = "auto"    // restore on macOS, sidecar elsewhere
+	MacForksRestore = "restore" // restore the resource fork and Finder info of the file; macOS only
+	MacForksSidecar = "sidecar" // a ._name file next to the file, outside __MACOSX
+	MacForksSkip    = "skip"    // do not extract them
+)
+
+var macForksPolicy = MacForksKeep
+
+const macOSXDir = "__MACOSX/"
+
+// an AppleDouble entry to be restored into its file after all files have been extracted
+type pendingFork struct {
+	entry  *zip.File
+	name   string // the name of the entry as a sidecar
+	target string // the output name of the file it belongs to
+}
+
+var pendingForks []pendingFork
+
+func checkMacForksPolicy() error {
+	switch macForksPolicy {
+	case MacForksKeep, MacForksAuto, MacForksSidecar, MacForksSkip:
+		return nil
+	case MacForksRestore:
+		if !canRestoreForks {
+			return fmt.Errorf("-mac-forks %s is supported only on macOS", macForksPolicy)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown -mac-forks policy %q", macForksPolicy)
+}
+
+// the policy for AppleDouble entries on this system
+func resolvedMacForksPolicy() string {
+	if macForksPolicy == MacForksAuto {
+		if runtime.GOOS == "darwin" && canRestoreForks {
+			return MacForksRestore
+		}
+		return MacForksSidecar
+	}
+	return macForksPolicy
+}
+
+// check if a name is the __MACOSX directory or a directory in it
+func isMacOSXDir(name string) bool {
+	return strings.HasPrefix(name, macOSXDir) && strings.HasSuffix(name, "/")
+}
+
+// the name of the file an AppleDouble entry belongs to, and the name of the entry as a sidecar of it
+func appleDoubleTarget(name string) (target, sidecar string, ok bool) {
+	rest := strings.TrimPrefix(name, macOSXDir)
+	dir, base := path.Split(rest)
+	if !strings.HasPrefix(base, "._") || len(base) == 2 {
+		return "", "", false
+	}
+	return dir + base[2:], rest, true
+}
+
+const appleDoubleMagic = 0x00051607
+
+// AppleDouble entry IDs
+const (
+	adResourceFork = 2
+	adFinderInfo   = 9
+)
+
+// get the resource fork and the Finder info from AppleDouble data
+func parseAppleDouble(data []byte) (rsrc, finderInfo []byte, err error) {
+	if len(data) < 26 || binary.BigEndian.Uint32(data) != appleDoubleMagic {
+		return nil, nil, errors.New("not an AppleDouble file")
+	}
+	n := int(binary.BigEndian.Uint16(data[24:]))
+	for i := 0; i < n; i++ {
+		e := 26 + 12*i
+		if e+12 > len(data) {
+			return nil, nil, errors.New("truncated AppleDouble header")
+		}
+		id := binary.BigEndian.Uint32(data[e:])
+		off := uint64(binary.BigEndian.Uint32(data[e+4:]))
+		length := uint64(binary.BigEndian.Uint32(data[e+8:]))
+		if off+length > uint64(len(data)) {
+			return nil, nil, errors.New("truncated AppleDouble entry")
+		}
+		switch id {
+		case adResourceFork:
+			rsrc = data[off : off+length]
+		case adFinderInfo:
+			finderInfo = data[off : off+length]
+			if len(finderInfo) > 32 { // followed by extended attributes
+				finderInfo = finderInfo[:32]
+			}
+		}
+	}
+	return
+}
+
+// restore the resource fork and the Finder info of an AppleDouble entry into its file.
+// Data that is not AppleDouble is extracted as a sidecar.
+func restorePendingFork(f pendingFork) error {
+	r, err := f.entry.Open()
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		return err
+	}
+	rsrc, finderInfo, err := parseAppleDouble(data)
+	if err != nil {
+		warnf(WarnEntry, "%s: %v; extracted as %s", f.entry.Name, err, f.name)
+		return writeFile(f.entry, f.name)
+	}
+	outpath := outputPath(f.target)
+	if _, err := os.Lstat(outpath); err != nil {
+		warnf(WarnEntry, "%s: the file it belongs to was not extracted; extracted as %s", f.entry.Name, f.name)
+		return writeFile(f.entry, f.name)
+	}
+	err = restoreFork(outpath, rsrc, finderInfo)
+	if err == nil && !quiet {
+		fmt.Printf("%s (resource fork and Finder info)\n", f.target)
+	}
+	return err
+}
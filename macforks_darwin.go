@@ -0,0 +1,33 @@
+//go:build darwin
+
+package main
+
+import (
+	"bytes"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+const canRestoreForks = true
+
+// write the resource fork and set the Finder info of a file or directory
+func restoreFork(outpath string, rsrc, finderInfo []byte) error {
+	st, err := os.Stat(outpath)
+	if err != nil {
+		return err
+	}
+	if len(rsrc) > 0 && !st.IsDir() {
+		err = os.WriteFile(outpath+"/..namedfork/rsrc", rsrc, 0666)
+		if err != nil {
+			return err
+		}
+	}
+	if len(finderInfo) == 32 && !bytes.Equal(finderInfo, make([]byte, 32)) {
+		err = unix.Setxattr(outpath, "com.apple.FinderInfo", finderInfo, 0)
+		if err != nil {
+			return &os.PathError{Op: "setxattr", Path: outpath, Err: err}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+//go:build !darwin
+
+package main
+
+import "errors"
+
+const canRestoreForks = false
+
+// resource forks and Finder info exist only on macOS
+func restoreFork(outpath string, rsrc, finderInfo []byte) error {
+	return errors.New("resource forks can be restored only on macOS")
+}
@@ -8,17 +8,23 @@ package main
 
 import (
 	"archive/zip"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
-	iconv "github.com/djimenez/iconv-go"
 	tty "github.com/mattn/go-tty"
+	"github.com/mixcode/codepage-unzip/codepagezip"
 )
 
 type CmdType int
@@ -27,12 +33,50 @@ const (
 	CmdNone CmdType = iota
 	CmdUnzip
 	CmdList
+	CmdAdd
+	CmdDelete
+	CmdRename
+	CmdComment
+	CmdTranslit
+	CmdCensus
+)
+
+// commands given as the first argument
+var subcommands = map[string]CmdType{
+	"add":      CmdAdd,
+	"delete":   CmdDelete,
+	"rename":   CmdRename,
+	"comment":  CmdComment,
+	"translit": CmdTranslit,
+	"census":   CmdCensus,
+}
+
+// policies for an existing, non-empty subdirectory made by -k
+const (
+	KeepDirMerge  = "merge"  // put files into the existing directory
+	KeepDirSuffix = "suffix" // use a new directory with a numbered suffix
+	KeepDirError  = "error"  // stop with an error
+)
+
+// the order to extract entries in
+const (
+	ReadOrderCD     = "cd"     // the order of the central directory
+	ReadOrderOffset = "offset" // the order of the data in the archive file
+)
+
+// what to do with an entry whose name ends with a slash but that has data
+const (
+	DirDataDir   = "dir"   // make the directory and ignore the data
+	DirDataFile  = "file"  // write the data to a file of the name without the slash
+	DirDataError = "error" // fail the entry
 )
 
 const (
 	UTF8 = "utf-8"
 
-	//FLAG_EFS = 0x800 // EFS: Language Encoding Flag: if set, the filename is in UTF-8
+	FLAG_ENCRYPTED       = 0x1   // the entry is encrypted
+	FLAG_DATA_DESCRIPTOR = 0x8   // the CRC and sizes follow the data
+	FLAG_EFS             = 0x800 // EFS: Language Encoding Flag: if set, the filename is in UTF-8
 )
 
 var (
@@ -43,37 +87,200 @@ var (
 
 	destDir = "." // output directory
 
-	overwrite   = false
-	quiet       = false
-	keepFileDir = false // make a subdirectory of the zip file and put files into there
+	overwrite     = false
+	quiet         = false
+	listEncodings = false        // print the available codepages
+	keepFileDir   = false        // make a subdirectory of the zip file and put files into there
+	keepDirPolicy = KeepDirMerge // what to do if the subdirectory of -k already exists
+	dirDataPolicy = DirDataDir   // what to do with directory entries that have data
+	writeMap      = false        // write a names.map file in the output directory
+	staging       = false        // extract into a temporary directory and move it into place at the end
+	useSandbox    = false        // confine all writes into the output directory at the kernel level
+
+	keepGoing    = false // skip failed entries and continue
+	entryTimeout = time.Duration(0)
+
+	transformCmd = ""          // external command to transform names
+	readOrder    = ReadOrderCD // order of extraction
+	smallFirst   = false       // extract small entries first
+	preallocate  = false       // reserve the space of each output file before writing
+	dirsOnly     = false       // make only the directories
+
+	checkpointFile = "" // file to record completed entries for resuming
+
+	maxEntries = 1000000 // refuse archives with more entries than this; 0 for no limit
+	maxDepth   = 100     // refuse entries with deeper paths than this; 0 for no limit
 )
 
+var errEncrypted = errors.New("the entry is encrypted, which is not supported")
+
 // show Yes/No prompt
 func promptYN(msg string, defaultYes bool) bool {
+	s := promptKey(msg)
+	if s == "y" {
+		return true
+	} else if s == "n" {
+		return false
+	}
+	return defaultYes
+}
+
+// prompt a message and read a single key from the terminal, in lower case.
+// Returns an empty string if the terminal is not available.
+func promptKey(msg string) string {
 	tt, err := tty.Open()
 	if err != nil {
-		return defaultYes
+		return ""
 	}
 	defer tt.Close()
 
 	fmt.Print(msg)
 	r, err := tt.ReadRune()
 	fmt.Print("\n")
-	if err == nil {
-		s := strings.ToLower(string(r))
-		if s == "y" {
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(string(r))
+}
+
+// ask whether to overwrite an existing file.
+// A diff is offered if both the file and the entry are small text files.
+func promptOverwrite(entry *zip.File, name, outpath string) bool {
+	fmt.Printf(tr("The output file '%s' already exists."), name)
+	old, new, ok := diffSources(entry, outpath)
+	if !ok {
+		return promptYN(tr(" Overwrite? (y/N)"), false)
+	}
+	for {
+		switch promptKey(tr(" Overwrite? (y/N/d=diff)")) {
+		case "y":
 			return true
-		} else if s == "n" {
+		case "d":
+			fmt.Print(unifiedDiff(old, new, outpath, name))
+			fmt.Printf("'%s'", name)
+		default:
 			return false
 		}
 	}
-	return defaultYes
 }
 
-func run() (err error) {
-	arg := flag.Args()
+// parse flags that come after non-flag arguments, and return the non-flag arguments
+func parseInterspersed(args []string) ([]string, error) {
+	var rest []string
+	for len(args) > 0 {
+		err := flag.CommandLine.Parse(args)
+		if err != nil {
+			return nil, err
+		}
+		args = flag.Args()
+		if len(args) > 0 {
+			rest = append(rest, args[0])
+			args = args[1:]
+		}
+	}
+	return rest, nil
+}
+
+// convert the filename of an entry
+func convertName(entry *zip.File) (name string, err error) {
+	return codepagezip.ConvertName(entry, convertFrom, convertTo)
+}
+
+// entries whose names could not be converted, and have generated names
+var unconverted = make(map[*zip.File]bool)
+
+// make a safe name for the i'th entry, whose name cannot be converted.
+// The extension is kept if it is plain ASCII.
+func unconvertedName(entry *zip.File, i int) string {
+	if strings.HasSuffix(entry.Name, "/") {
+		return fmt.Sprintf("unconverted_%04d/", i)
+	}
+	ext := path.Ext(entry.Name)
+	if len(ext) < 2 || len(ext) > 10 {
+		ext = ".bin"
+	}
+	for _, c := range ext[1:] {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9') {
+			ext = ".bin"
+			break
+		}
+	}
+	return fmt.Sprintf("unconverted_%04d%s", i, ext)
+}
+
+func run(arg []string) (err error) {
+	err = applyPreset(presetName)
+	if err != nil {
+		return
+	}
+	err = selectSchemes(translitNames)
+	if err != nil {
+		return
+	}
+
+	switch cmd {
+	case CmdAdd:
+		return runAdd(arg)
+	case CmdDelete:
+		return runDelete(arg)
+	case CmdRename:
+		return runRename(arg)
+	case CmdComment:
+		return runComment(arg)
+	case CmdTranslit:
+		return runTranslit(arg)
+	case CmdCensus:
+		return runCensus(arg)
+	}
+
 	if len(arg) == 0 {
-		return fmt.Errorf("a zip filename must be given (use --help for help)")
+		return errors.New(tr("a zip filename must be given (use --help for help)"))
+	}
+	if fsNames != FsNamesWarn && fsNames != FsNamesFix && fsNames != FsNamesOff {
+		return fmt.Errorf(tr("unknown -fs-names policy '%s'"), fsNames)
+	}
+	if readOrder != ReadOrderCD && readOrder != ReadOrderOffset {
+		return fmt.Errorf(tr("unknown -read-order '%s'"), readOrder)
+	}
+	if dirDataPolicy != DirDataDir && dirDataPolicy != DirDataFile && dirDataPolicy != DirDataError {
+		return fmt.Errorf(tr("unknown -dir-data policy '%s'"), dirDataPolicy)
+	}
+	err = parseRoutes(routeSpec)
+	if err != nil {
+		return
+	}
+	err = checkSymlinkPolicy()
+	if err != nil {
+		return
+	}
+	err = checkSpecialsPolicy()
+	if err != nil {
+		return
+	}
+	err = checkFsyncPolicy()
+	if err != nil {
+		return
+	}
+	err = checkADSPolicy()
+	if err != nil {
+		return
+	}
+	err = checkMacForksPolicy()
+	if err != nil {
+		return
+	}
+	err = parseSourceTZ()
+	if err != nil {
+		return
+	}
+	err = parseFixExtensions(fixExtensions)
+	if err != nil {
+		return
+	}
+	parseConvertContent(convertContent)
+	err = checkSandboxOptions()
+	if err != nil {
+		return
 	}
 
 	// check the output directory
@@ -83,60 +290,750 @@ func run() (err error) {
 			return err
 		}
 		if !st.IsDir() {
-			return fmt.Errorf("the destination path is not a directory")
+			return errors.New(tr("the destination path is not a directory"))
 		}
 	}
 
 	// make a zip reader
 	zipname := arg[0]
-	zr, err := zip.OpenReader(zipname)
+	crashArchive = zipname
+	if isURL(zipname) {
+		err = checkRemoteOptions()
+		if err != nil {
+			return
+		}
+	} else if format, err := detectSingleFormat(zipname); err != nil {
+		return err
+	} else if format != FormatNone && checkpointFile != "" {
+		return errors.New("-checkpoint is supported only for zip archives")
+	} else if format == FormatISO {
+		return runISO(zipname)
+	} else if format != FormatNone {
+		return runSingle(zipname, format)
+	}
+
+	// a cached listing of the same archive with the same options
+	var listOut io.Writer = os.Stdout
+	if cmd == CmdList && listCache && !explainNames && exportFile == "" {
+		var cacheFile string
+		cacheFile, err = listCacheFile(zipname)
+		if err != nil {
+			return
+		}
+		if printCachedList(cacheFile) {
+			return nil
+		}
+		listing := &bytes.Buffer{}
+		listOut = io.MultiWriter(os.Stdout, listing)
+		defer func() {
+			if err == nil && listing.Len() > 0 {
+				saveListCache(cacheFile, listing)
+			}
+		}()
+	}
+
+	zr, closeZip, err := openZip(zipname)
 	if err != nil {
 		return
 	}
-	defer zr.Close()
+	defer closeZip()
+	registerDecompressors(zr)
 
-	if keepFileDir { // keep-organized; append the zip file name to the output path
-		// append the basename of ZIP to the output path
-		_, file := filepath.Split(zipname)
-		ext := filepath.Ext(file)
-		basename := file[:len(file)-len(ext)]
-		destDir = filepath.Join(destDir, basename)
+	if wantsDetection() {
+		defer applyDetection(zr.File)()
 	}
 
-	// write files
-	for _, fileEntry := range zr.File {
-		// convert the filename
-		cf := convertFrom
-		//if fileEntry.Flags&FLAG_EFS != 0 {
-		if !fileEntry.NonUTF8 { // Note that EFS flag checking is done in archive/zip package
-			cf = UTF8
+	if passwordList != "" {
+		return findPasswords(zr.File)
+	}
+
+	if zeroCopy && cmd == CmdUnzip {
+		zipFile, err = os.Open(zipname)
+		if err != nil {
+			return
+		}
+		defer zipFile.Close()
+	}
+
+	if maxEntries > 0 && len(zr.File) > maxEntries {
+		return fmt.Errorf("the archive has %d entries, which exceeds the limit of %d (see -max-entries)", len(zr.File), maxEntries)
+	}
+
+	var transform *transformProc
+	if transformCmd != "" {
+		transform, err = startTransform(transformCmd)
+		if err != nil {
+			return
+		}
+	}
+
+	// names the destination filesystem may not accept
+	var fsc fsConstraint
+	if cmd == CmdUnzip && fsNames != FsNamesOff {
+		fsc = destConstraint(destDir)
+		if fsc.asciiOnly && fsNames == FsNamesFix && !asciiSlugs {
+			if !quiet {
+				fmt.Printf("The output directory is on a %s filesystem without unicode names; using -ascii-slugs\n", fsc.fsType)
+			}
+			asciiSlugs = true
+		}
+	}
+
+	var slugs *slugger
+	if asciiSlugs {
+		slugs = newSlugger()
+	}
+
+	// convert the filenames
+	names := make([]string, len(zr.File))
+	skip := make([]bool, len(zr.File))
+	byConverted := make(map[string]int)      // the converted names, for AppleDouble entries to find their files
+	forkTargets := make(map[int]string)      // AppleDouble entries to restore, and the converted names of their files
+	archiveNames := entryNameSet(zr.File)    // to tell alternate data streams from names with a colon
+	whys := make([]*nameTrace, len(zr.File)) // printed once every reason to skip an entry is known
+	noTime := 0                              // entries with a zero DOS date, which keep the time of extraction
+	for i, fileEntry := range zr.File {
+		why := newNameTrace(fileEntry)
+		whys[i] = why
+		if cmd == CmdUnzip && !codepagezip.HasModTime(fileEntry) {
+			noTime++
+		} else if cmd == CmdUnzip && codepagezip.SkewedTime(fileEntry.Modified) {
+			warnf(WarnTimestamp, "%q has an implausible modification time %v", fileEntry.Name, fileEntry.Modified)
+		}
+		name, err := convertName(fileEntry)
+		if err != nil {
+			// do not give up the whole archive for a name
+			name = unconvertedName(fileEntry, i)
+			unconverted[fileEntry] = true
+			warnf(WarnLossy, "%q: %v; using the name %s", fileEntry.Name, err, name)
+			why.add("decoding failed; using a generated name: %s", name)
+			if cmd == CmdUnzip && !writeMap && exportFile == "" {
+				// record the raw name
+				fmt.Fprintf(os.Stderr, "The raw names are recorded in %s\n", namesMapFilename)
+				writeMap = true
+			}
+		}
+		if transform != nil {
+			before := name
+			name, skip[i], err = transform.apply(fileEntry, name)
+			if err != nil {
+				transform.Close()
+				return err
+			}
+			why.changed("renamed by the transform command", before, name)
+			if skip[i] {
+				why.skip("by the transform command")
+			}
+		}
+		byConverted[name] = i
+		if macForksPolicy != MacForksKeep && !skip[i] {
+			if isMacOSXDir(name) {
+				skip[i] = true
+				why.skip("the __MACOSX directory (-mac-forks %s)", macForksPolicy)
+			} else if target, sidecar, ok := appleDoubleTarget(name); ok {
+				switch resolvedMacForksPolicy() {
+				case MacForksSkip:
+					skip[i] = true
+					why.skip("an AppleDouble entry (-mac-forks %s)", macForksPolicy)
+				case MacForksRestore:
+					forkTargets[i] = target
+					fallthrough
+				case MacForksSidecar:
+					before := name
+					name = sidecar
+					why.changed("AppleDouble sidecar", before, name)
+				}
+			}
+		}
+		stream := ""
+		if file, st, ok := splitStream(name); !ok {
+			name = file
+		} else if isStreamEntry(fileEntry.Name, archiveNames) {
+			name, stream = file, st
+		}
+		if cmd == CmdUnzip && codepagezip.SuspiciousName(name) {
+			warnf(WarnName, "%s is an absolute path or leads outside; it is extracted as %s", name, codepagezip.SanitizePath(name))
+		}
+		if stripControls {
+			before := name
+			name = stripControlChars(name)
+			why.changed("control characters removed", before, name)
+		}
+		if widthFold {
+			before := name
+			name = foldWidth(name)
+			why.changed("width folded", before, name)
+		}
+		if maxDepth > 0 && cmd == CmdUnzip {
+			if d := pathDepth(name); d > maxDepth {
+				return fmt.Errorf("%s is %d levels deep, which exceeds the limit of %d (see -max-depth)", name, d, maxDepth)
+			}
+		}
+		if slugs != nil && !skip[i] {
+			before := name
+			name = slugs.name(name)
+			why.changed("ASCII slug", before, name)
+		}
+		if fsc.windowsSafe && fsNames == FsNamesFix {
+			before := name
+			name = windowsSafePath(name)
+			why.changed(fmt.Sprintf("made safe for %s", fsc.fsType), before, name)
+		}
+		if cmd == CmdUnzip {
+			if fixed := limitPath(name, fsc.needWindowsNames()); fixed != name {
+				if !quiet {
+					fmt.Fprintf(os.Stderr, "Renamed '%s' to '%s'\n", name, fixed)
+				}
+				why.changed("reserved or too long name renamed", name, fixed)
+				name = fixed
+			}
+		}
+		if stream != "" {
+			if adsPolicy == ADSSkip {
+				if cmd == CmdUnzip && !quiet && !skip[i] {
+					fmt.Printf("skipping alternate data stream %s:%s\n", name, stream)
+				}
+				skip[i] = true
+				why.skip("an alternate data stream (-ads %s)", adsPolicy)
+			} else {
+				before := name
+				name = streamName(name, stream)
+				streamEntries[fileEntry] = true
+				why.changed(fmt.Sprintf("alternate data stream %s", stream), before, name)
+			}
+		}
+		names[i] = name
+	}
+	if noTime > 0 && !quiet {
+		// not a warning: the archive simply has no times to restore
+		fmt.Fprintf(os.Stderr, "%d entries have no modification time; their files keep the time of extraction\n", noTime)
+	}
+	forks := make(map[int]string) // AppleDouble entries to restore, and the output names of their files
+	for i, target := range forkTargets {
+		t, ok := byConverted[target]
+		if !ok {
+			t, ok = byConverted[target+"/"]
+		}
+		if ok && !skip[t] {
+			forks[i] = names[t]
+		}
+	}
+	if fsNames == FsNamesWarn && (fsc.windowsSafe || fsc.asciiOnly) {
+		warnFsNames(fsc, names)
+	}
+	if transform != nil {
+		err = transform.Close()
+		if err != nil {
+			return fmt.Errorf("transform: %w", err)
+		}
+	}
+
+	if updateOnly && cmd == CmdUnzip {
+		defer reportUpToDate()
+	}
+	if sinceArchive != "" {
+		var n int
+		was := slices.Clone(skip)
+		n, err = skipUnchanged(sinceArchive, zr.File, skip)
+		if err != nil {
+			return fmt.Errorf("%s: %w", sinceArchive, err)
+		}
+		defer reportUnchanged(n)
+		for i := range skip {
+			if skip[i] && !was[i] {
+				whys[i].skip("unchanged since %s", sinceArchive)
+			}
+		}
+	}
+	for i, why := range whys {
+		why.print(names[i])
+	}
+
+	if exportFile != "" {
+		return exportEntries(exportFile, zr.File, names, skip)
+	}
+
+	if keepFileDir { // keep-organized; append the zip file name to the output path
+		basename := keepDirName(zipname)
+		if cmd == CmdUnzip {
+			destDir, err = keepDirPath(filepath.Join(destDir, basename))
+			if err != nil {
+				return
+			}
+		} else {
+			destDir = filepath.Join(destDir, basename)
 		}
-		name := fileEntry.Name
-		name, err = iconv.ConvertString(name, cf, convertTo) // Note that it's safe to store non-UTF8 bytes in Go string, because it's internally just a []byte
+	}
+
+	if cmd == CmdUnzip {
+		var finish func(*error)
+		finish, err = openOutput(zipname, slugs)
 		if err != nil {
-			err = fmt.Errorf("converting from %s to %s: %w", convertFrom, convertTo, err)
 			return
 		}
+		defer finish(&err)
+	}
+
+	// the order to process entries
+	order := make([]int, len(zr.File))
+	for i := range order {
+		order[i] = i
+	}
+	if readOrder == ReadOrderOffset && cmd == CmdUnzip {
+		// read the archive sequentially, whatever order the central directory lists the entries in
+		offsets := make([]int64, len(zr.File))
+		for i, f := range zr.File {
+			offsets[i], err = f.DataOffset()
+			if err != nil {
+				return
+			}
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			return offsets[order[a]] < offsets[order[b]]
+		})
+	}
+	if smallFirst && cmd == CmdUnzip {
+		sort.SliceStable(order, func(a, b int) bool {
+			return zr.File[order[a]].UncompressedSize64 < zr.File[order[b]].UncompressedSize64
+		})
+	}
+
+	if len(streamEntries) > 0 {
+		// a stream is written into its file, which must exist first
+		sort.SliceStable(order, func(a, b int) bool {
+			return !streamEntries[zr.File[order[a]]] && streamEntries[zr.File[order[b]]]
+		})
+	}
+
+	var stats *extractStats
+	if showStats && cmd == CmdUnzip {
+		stats = newExtractStats()
+		defer stats.print()
+	}
+
+	// encrypted entries cannot be extracted; they are flagged in the list and skipped
+	encrypted := 0
+	for i, f := range zr.File {
+		if !skip[i] && f.Flags&FLAG_ENCRYPTED != 0 {
+			encrypted++
+		}
+	}
+
+	// write files
+	failed := 0
+	for _, i := range order {
+		fileEntry := zr.File[i]
+		name := names[i]
+		if skip[i] {
+			continue
+		}
 
 		switch cmd {
 		case CmdList:
-			fmt.Printf("%s\n", name)
+			if encrypted > 0 {
+				flagCol := " "
+				if fileEntry.Flags&FLAG_ENCRYPTED != 0 {
+					flagCol = "E"
+				}
+				fmt.Fprintf(listOut, "%s %s\n", flagCol, name)
+			} else {
+				fmt.Fprintf(listOut, "%s\n", name)
+			}
 
 		case CmdUnzip:
+			if ckpt != nil && ckpt.done(fileEntry, destDir) {
+				continue
+			}
+			if target, ok := forks[i]; ok {
+				pendingForks = append(pendingForks, pendingFork{fileEntry, name, target})
+				continue
+			}
+			t0 := time.Now()
 			err = writeFile(fileEntry, name)
+			if err == nil && stats != nil {
+				stats.add(fileEntry, time.Since(t0))
+			}
 			if err != nil {
+				if !keepGoing {
+					return
+				}
+				fmt.Fprintf(os.Stderr, "Error: %s: %v\n", name, err)
+				failed++
+				err = nil
+			}
+		}
+	}
+	for _, l := range pendingLinks {
+		err = makeLink(l)
+		if err != nil {
+			if !keepGoing {
 				return
 			}
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", l.name, err)
+			failed++
+			err = nil
+		}
+	}
+	for _, f := range pendingForks {
+		err = restorePendingFork(f)
+		if err != nil {
+			if !keepGoing {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", f.name, err)
+			failed++
+			err = nil
+		}
+	}
+	if cmd == CmdUnzip && scanText {
+		reportMojibake(extractedFiles)
+	}
+	if cmd == CmdUnzip && syncDirs() {
+		err = syncDirtyDirs()
+		if err != nil {
+			return
+		}
+	}
+	if encrypted > 0 {
+		if cmd == CmdList {
+			fmt.Fprintf(os.Stderr, "%d entries are encrypted (marked E)\n", encrypted)
+		} else if cmd == CmdUnzip {
+			fmt.Fprintf(os.Stderr, tr("%d entries are encrypted and were not extracted\n"), encrypted)
 		}
 	}
+	if encodingStats {
+		if e := recordEncoding(zr.File); e != nil {
+			fmt.Fprintf(os.Stderr, "Warning: cannot record the encoding statistics: %v\n", e)
+		}
+	}
+	err = warningSummary()
+	if err != nil {
+		return
+	}
+	if failed > 0 {
+		err = &failedEntriesError{failed}
+	}
 
 	return
 }
 
+// check if a directory has no entries
+func isEmptyDir(dir string) (bool, error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	_, err = f.Readdirnames(1)
+	if err == io.EOF {
+		return true, nil
+	}
+	return false, err
+}
+
+// the name of the subdirectory of -k: the basename of the archive without its extension
+func keepDirName(archive string) string {
+	_, file := filepath.Split(archive)
+	if isURL(archive) {
+		file = remoteBaseName(archive)
+	}
+	ext := filepath.Ext(file)
+	return codepagezip.SanitizeComponent(file[:len(file)-len(ext)])
+}
+
+// open a ZIP file, or an archive at an http or https URL
+func openZip(zipname string) (zr *zip.Reader, close func() error, err error) {
+	if isURL(zipname) {
+		rf, err := openRemote(zipname)
+		if err != nil {
+			return nil, nil, err
+		}
+		zr, err = zip.NewReader(rf, rf.Size())
+		return zr, func() error { return nil }, err
+	}
+	rc, err := zip.OpenReader(zipname)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &rc.Reader, rc.Close, nil
+}
+
+// choose the subdirectory for -k according to keepDirPolicy
+func keepDirPath(dir string) (string, error) {
+	st, err := os.Stat(dir)
+	if os.IsNotExist(err) {
+		return dir, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if st.IsDir() {
+		empty, err := isEmptyDir(dir)
+		if err != nil {
+			return "", err
+		}
+		if empty {
+			return dir, nil
+		}
+	}
+
+	switch keepDirPolicy {
+	case KeepDirMerge:
+		if !st.IsDir() {
+			return "", fmt.Errorf("%s exists and is not a directory", dir)
+		}
+		if !quiet {
+			fmt.Printf("Extracting into the existing directory '%s'\n", dir)
+		}
+		return dir, nil
+
+	case KeepDirSuffix:
+		for i := 1; ; i++ {
+			d := fmt.Sprintf("%s-%d", dir, i)
+			_, err := os.Stat(d)
+			if os.IsNotExist(err) {
+				return d, nil
+			}
+			if err != nil {
+				return "", err
+			}
+		}
+
+	case KeepDirError:
+		return "", fmt.Errorf("the output directory %s already exists", dir)
+	}
+	return "", fmt.Errorf("unknown -k policy '%s'", keepDirPolicy)
+}
+
 var (
 	hasPath = make(map[string]bool)
+	nameMap *namesMap   // names.map writer; nil if not requested
+	box     *sandbox    // sandbox of the output directory; nil if not requested
+	ckpt    *checkpoint // completed entries; nil if not requested
 )
 
+// prepare the output directory of an archive as the options ask: lock it, load the checkpoint,
+// and begin staging, the sandbox and the map files.
+// The returned function finishes them in reverse order, given the address of the result of the extraction.
+func openOutput(archive string, slugs *slugger) (finish func(err *error), err error) {
+	var done []func(err *error)
+	finish = func(err *error) {
+		for i := len(done) - 1; i >= 0; i-- {
+			done[i](err)
+		}
+	}
+	defer func() {
+		if err != nil {
+			finish(&err)
+			finish = nil
+		}
+	}()
+
+	if !noLock {
+		var unlock func()
+		unlock, err = lockDestination(destDir)
+		if err != nil {
+			return
+		}
+		done = append(done, func(*error) { unlock() })
+	}
+
+	if checkpointFile != "" {
+		ckpt, err = loadCheckpoint(checkpointFile, archive)
+		if err != nil {
+			return
+		}
+		c := ckpt
+		done = append(done, func(err *error) {
+			e := c.save()
+			if *err == nil {
+				*err = e
+			}
+		})
+	}
+
+	if staging {
+		final := destDir
+		if ckpt != nil && ckpt.Staging != "" {
+			// resume into the staging directory the completed entries are in
+			destDir, err = resumeStaging(ckpt.Staging, final)
+		} else {
+			destDir, err = beginStaging(final)
+		}
+		if err != nil {
+			return
+		}
+		if ckpt != nil {
+			ckpt.Staging, err = filepath.Abs(destDir)
+			if err != nil {
+				return
+			}
+		}
+		stagingFinal = final
+		tmp := destDir
+		done = append(done, func(err *error) {
+			stagingFinal = ""
+			var fe *failedEntriesError
+			if *err == nil || errors.As(*err, &fe) { // keep what -keep-going has extracted
+				if e := commitStaging(tmp, final); e != nil {
+					*err = e
+				} else {
+					if ckpt != nil {
+						ckpt.moved(tmp, final)
+					}
+					if syncDirs() {
+						// the directory containing the renamed output directory
+						dirtyDirs[filepath.Dir(filepath.Clean(final))] = true
+						if e := syncDirtyDirs(); e != nil && *err == nil {
+							*err = e
+						}
+					}
+				}
+			}
+			if *err != nil && !errors.As(*err, &fe) && ckpt == nil {
+				abortStaging(tmp) // with a checkpoint, it is kept for resuming
+			}
+		})
+	}
+
+	if useSandbox {
+		err = os.MkdirAll(destDir, fs.ModePerm)
+		if err != nil {
+			return
+		}
+		box, err = openSandbox(destDir)
+		if err != nil {
+			return
+		}
+		b := box
+		done = append(done, func(*error) { b.Close() })
+	}
+
+	if slugs != nil {
+		err = os.MkdirAll(destDir, fs.ModePerm)
+		if err != nil {
+			return
+		}
+		err = slugs.writeMap(destDir)
+		if err != nil {
+			return
+		}
+	}
+
+	if writeMap {
+		nameMap, err = createNamesMap(destDir)
+		if err != nil {
+			return
+		}
+		m := nameMap
+		done = append(done, func(err *error) {
+			e := m.Close()
+			if *err == nil {
+				*err = e
+			}
+		})
+	}
+	return
+}
+
+// get the codepage of the filename of a zip entry
+func nameEncoding(entry *zip.File) string {
+	return codepagezip.NameEncoding(entry, convertFrom)
+}
+
+// the path an entry name is extracted to
+func outputPath(name string) string {
+	return filepath.Join(destDir, filepath.FromSlash(routeDir(name)), filepath.FromSlash(codepagezip.SanitizePath(name)))
+}
+
+// make a directory and its parents for an entry
+func makeDir(path string) error {
+	if box != nil {
+		return box.MkdirAll(path)
+	}
+	return os.MkdirAll(path, fs.ModePerm)
+}
+
+// create an output file for an entry
+func createFile(path string) (*os.File, error) {
+	if box != nil {
+		return box.Create(path)
+	}
+	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC|oNoFollow, 0666)
+}
+
+// create a new output file that must not exist yet
+func createNewFile(path string, perm fs.FileMode) (*os.File, error) {
+	if box != nil {
+		return box.CreateNew(path, perm)
+	}
+	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+}
+
+// make a symlink in the output directory
+func makeSymlink(target, path string) error {
+	if box != nil {
+		return box.Symlink(target, path)
+	}
+	return os.Symlink(target, path)
+}
+
+// make a hard link in the output directory
+func makeHardLink(oldpath, newpath string) error {
+	if box != nil {
+		return box.Link(oldpath, newpath)
+	}
+	return os.Link(oldpath, newpath)
+}
+
+// rename a file in the output directory
+func renameFile(oldpath, newpath string) error {
+	if box != nil {
+		return box.Rename(oldpath, newpath)
+	}
+	return os.Rename(oldpath, newpath)
+}
+
+// remove a file in the output directory
+func removeFile(path string) error {
+	if box != nil {
+		return box.Remove(path)
+	}
+	return os.Remove(path)
+}
+
+// reject options whose writes -sandbox cannot confine
+func checkSandboxOptions() error {
+	if !useSandbox {
+		return nil
+	}
+	if staging {
+		return errors.New("-sandbox cannot be used with -staging, which moves the files into place outside of the sandbox")
+	}
+	if backupExisting.dir != "" {
+		dest, err := filepath.Abs(destDir)
+		if err != nil {
+			return err
+		}
+		dir, err := filepath.Abs(backupExisting.dir)
+		if err != nil {
+			return err
+		}
+		if !isBeneath(dest, dir) {
+			return errors.New("-sandbox needs the -backup-existing directory to be inside the output directory")
+		}
+	}
+	return nil
+}
+
+// set the timestamps of a file in the output directory
+func setFileTimes(path string, atime, mtime time.Time) error {
+	if box != nil {
+		return box.Chtimes(path, atime, mtime)
+	}
+	return os.Chtimes(path, atime, mtime)
+}
+
 func dbgj(e any) string {
 	s, _ := json.Marshal(e)
 	return string(s)
@@ -147,11 +1044,85 @@ func writeFile(entry *zip.File, name string) (err error) {
 	if name == "" {
 		return fmt.Errorf("empty filename")
 	}
-	outpath := filepath.Join(destDir, name)
+	outpath := outputPath(name)
 
-	if (name[len(name)-1] == '/' || name[len(name)-1] == '\\') && entry.UncompressedSize64 == 0 {
+	if box != nil && entry.Mode()&(fs.ModeDevice|fs.ModeCharDevice|fs.ModeNamedPipe|fs.ModeSocket|fs.ModeSetuid|fs.ModeSetgid) != 0 {
+		return fmt.Errorf("refusing %s with file mode %v in sandbox mode", name, entry.Mode())
+	}
+
+	if dirsOnly {
+		// make the directory of a directory entry, or the parent directory of any other entry
+		dir := outpath
+		if !strings.HasSuffix(name, "/") && !strings.HasSuffix(name, "\\") {
+			dir = filepath.Dir(outpath)
+		}
+		if hasPath[dir] {
+			return nil
+		}
+		err = checkDirBeneath(destDir, dir)
+		if err != nil {
+			return
+		}
+		err = makeDir(dir)
+		if err != nil {
+			return
+		}
+		hasPath[dir] = true
+		if rel, e := filepath.Rel(destDir, dir); e == nil && rel != "." && !quiet {
+			fmt.Printf("%s/\n", filepath.ToSlash(rel))
+		}
+		return
+	}
+
+	if entry.Flags&FLAG_ENCRYPTED != 0 {
+		if keepGoing {
+			return errEncrypted
+		}
+		return fmt.Errorf("%s: %w (use -keep-going to extract the other entries)", name, errEncrypted)
+	}
+
+	if entry.Mode()&fs.ModeSymlink != 0 && symlinkPolicy != SymlinkFile {
+		return deferSymlink(entry, name, outpath)
+	}
+	if entry.Mode()&specialModes != 0 {
+		return writeSpecial(entry, name, outpath)
+	}
+
+	isDir := name[len(name)-1] == '/' || name[len(name)-1] == '\\'
+	if isDir && entry.UncompressedSize64 > 0 {
+		switch dirDataPolicy {
+		case DirDataError:
+			return fmt.Errorf("directory entry %s has %d bytes of data", name, entry.UncompressedSize64)
+		case DirDataFile:
+			warnf(WarnEntry, "directory entry %s has data; writing it as a file", name)
+			isDir = false
+		default:
+			warnf(WarnEntry, "directory entry %s has %d bytes of data, which are ignored", name, entry.UncompressedSize64)
+		}
+	}
+	if isDir {
 		// the entry is a directory
-		err = os.MkdirAll(outpath, fs.ModePerm)
+		err = checkDirBeneath(destDir, outpath)
+		if err != nil {
+			return
+		}
+		err = makeDir(outpath)
+		if err == nil {
+			err = entryDone(entry, outpath)
+		}
+		return
+	}
+
+	if len(fixExtTypes) > 0 {
+		if ext := sniffExtension(entry, name); ext != "" {
+			reportFixedExtension(name, ext)
+			name += ext
+			outpath += ext
+		}
+	}
+
+	err = checkDirBeneath(destDir, filepath.Dir(outpath))
+	if err != nil {
 		return
 	}
 
@@ -160,7 +1131,7 @@ func writeFile(entry *zip.File, name string) (err error) {
 		if _, ok := err.(*fs.PathError); ok { // intermediate path error
 			// try to create intermediate paths
 			path := filepath.Dir(outpath)
-			err = os.MkdirAll(path, fs.ModePerm)
+			err = makeDir(path)
 			if err != nil {
 				return
 			}
@@ -168,15 +1139,26 @@ func writeFile(entry *zip.File, name string) (err error) {
 			st, err = os.Stat(outpath)
 		}
 	}
+	existing := outpath // the file the entry replaces; while staging, it is in the final output directory
+	if stagingFinal != "" && os.IsNotExist(err) {
+		existing = finalPath(outpath)
+		st, err = os.Stat(existing)
+	}
 	if !os.IsNotExist(err) {
 		if st.IsDir() {
 			// a directory with the same name exists
+			if entry.UncompressedSize64 == 0 {
+				warnf(WarnSkipped, "skipping empty file %s; a directory with the same name exists", name)
+				return nil
+			}
 			return fmt.Errorf("cannot create file %s", name)
 		}
-		if !overwrite {
-			fmt.Printf("The output file '%s' already exists.", name)
-			yes := promptYN(" Overwrite? (y/N)", false)
-			if !yes {
+		if updateOnly {
+			if !needsUpdate(entry, st) {
+				return nil
+			}
+		} else if !overwrite {
+			if !promptOverwrite(entry, name, existing) {
 				// ignore this file
 				return nil
 			}
@@ -186,7 +1168,7 @@ func writeFile(entry *zip.File, name string) (err error) {
 	if !quiet {
 		fmt.Printf("%s\n", name)
 	}
-	fi, err := entry.Open()
+	fi, err := openEntry(entry)
 	if err != nil {
 		return
 	}
@@ -198,7 +1180,7 @@ func writeFile(entry *zip.File, name string) (err error) {
 		st, err = os.Stat(path)
 		if os.IsNotExist(err) {
 			// make the path
-			err = os.MkdirAll(path, fs.ModePerm)
+			err = makeDir(path)
 			if err != nil {
 				return
 			}
@@ -210,58 +1192,147 @@ func writeFile(entry *zip.File, name string) (err error) {
 		}
 	}
 
-	fo, err := os.Create(outpath)
+	// do not write through an existing symlink; replace it
+	if lst, e := os.Lstat(outpath); e == nil && lst.Mode()&fs.ModeSymlink != 0 {
+		err = removeFile(outpath)
+		if err != nil {
+			return
+		}
+	} else if e == nil && lst.Mode().IsRegular() && backupExisting.enabled {
+		err = backupFile(destDir, outpath)
+		if err != nil {
+			return
+		}
+	}
+
+	fo, outpath, err := createFileRetry(outpath, name)
 	if err != nil {
 		return
 	}
 	defer fo.Close()
-	sz, err := io.Copy(fo, fi)
+	convert := wantsContentConversion(name)
+	if preallocate && !convert {
+		err = preallocateFile(fo, int64(entry.UncompressedSize64))
+		if err != nil {
+			fo.Close()
+			removeFile(outpath)
+			return
+		}
+	}
+	var sz int64
+	if convert {
+		sz, err = copyConverted(fo, fi, entry.UncompressedSize64)
+	} else if zeroCopy && entry.Method == zip.Store && entry.Flags&FLAG_ENCRYPTED == 0 {
+		sz, err = copyStored(fo, entry)
+	} else {
+		sz, err = copyEntry(fo, fi, entry.UncompressedSize64)
+	}
 	if err != nil {
+		// do not leave a broken file
+		fo.Close()
+		removeFile(outpath)
 		return
 	}
 	if sz != int64(entry.UncompressedSize64) {
-		err = fmt.Errorf("decompressed size does not match")
+		fo.Close()
+		removeFile(outpath)
+		err = fmt.Errorf("the entry data does not match its declared size; the stream may be corrupted")
+		return
+	}
+	if syncFiles() {
+		err = fo.Sync()
+		if err != nil {
+			return
+		}
+	}
+	err = fo.Close()
+	if err != nil {
+		return
+	}
+	err = restoreModTime(entry, outpath)
+	if err != nil {
+		return
 	}
+	return entryDone(entry, outpath)
+}
 
+// record an extracted entry
+func entryDone(entry *zip.File, outpath string) (err error) {
+	markDirty(outpath)
+	if scanText && entry.Mode().IsRegular() {
+		extractedFiles = append(extractedFiles, outpath)
+	}
+	if nameMap != nil {
+		encoding := nameEncoding(entry)
+		if unconverted[entry] {
+			encoding = "unconverted"
+		}
+		err = nameMap.add(entry.Name, encoding, outpath)
+		if err != nil {
+			return
+		}
+	}
+	if ckpt != nil {
+		err = ckpt.record(entry, outpath)
+	}
 	return
 }
 
 func main() {
+	defer handleCrash()
 
 	flag.Usage = func() {
-		fo := flag.CommandLine.Output()
-		fmt.Fprintf(fo, "Decompress a ZIP file with non-unicode filenames.\n")
-		fmt.Fprintf(fo, "\n")
-		fmt.Fprintf(fo, "Usage: %s [flags] [-f codepage] ZIPfile\n", os.Args[0])
-		fmt.Fprintf(fo, "\n")
-		fmt.Fprintf(fo, "Filenames are converted from the specified codepage to unicode.\n")
-		fmt.Fprintf(fo, "See iconv man page for avaliable codepages.\n")
-		fmt.Fprintf(fo, "\n")
-
-		fmt.Fprintf(fo, "Flags:\n")
-		flag.PrintDefaults()
-		fmt.Fprintf(fo, "\n")
+		printUsage(flag.CommandLine.Output(), os.Args[0])
 	}
 
 	flagList := false
-	flag.BoolVar(&flagList, "l", false, "print filenames without extracting")
-	flag.StringVar(&destDir, "d", destDir, "Directory to which to extract files")
-	flag.BoolVar(&overwrite, "o", overwrite, "overwrite existing files")
-	flag.BoolVar(&keepFileDir, "k", keepFileDir, "keep-organized; make a subdirectory of the same name with ZIP file and put files there")
-	flag.BoolVar(&quiet, "q", quiet, "suppress messages")
-	flag.StringVar(&convertFrom, "f", convertFrom, "codepage of filenames in ZIP")
-	flag.StringVar(&convertTo, "t", convertTo, "codepage of output filenames. WARNING: change this only if you know exactly what you are doing!")
+	registerFlags(flagSpecs(&flagList))
 	flag.Parse()
 
-	if flagList {
+	args := flag.Args()
+	if flag.Arg(0) == "help" {
+		if len(args) > 1 && (args[1] == "--man" || args[1] == "-man") {
+			writeManPage(os.Stdout, filepath.Base(os.Args[0]))
+		} else {
+			printUsage(os.Stdout, os.Args[0])
+		}
+		return
+	}
+	if listEncodings {
+		err := codepagezip.WriteEncodings(os.Stdout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, tr("Error: %v\n"), err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+	if flag.Arg(0) == "version" {
+		printVersion(os.Stdout, filepath.Base(os.Args[0]))
+		return
+	}
+
+	var err error
+	if c, ok := subcommands[flag.Arg(0)]; ok {
+		cmd = c
+		args, err = parseInterspersed(args[1:])
+		if err != nil {
+			os.Exit(2)
+		}
+	} else if flagList {
 		cmd = CmdList
 	} else {
 		cmd = CmdUnzip
 	}
 
-	err := run()
+	if wizard {
+		err = runWizard(args)
+	} else if (archivesFrom0 != "" || manifestFile != "" || destPerArchive != "" || batchJobs > 1 || useMarkers || len(args) > 1) && (cmd == CmdUnzip || cmd == CmdList) {
+		err = runBatch(args)
+	} else {
+		err = run(args)
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err.Error())
+		fmt.Fprintf(os.Stderr, tr("Error: %v\n"), err.Error())
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const markerFilename = ".extracted-ok"
+
+var useMarkers = false // record extracted archives in the destination and skip them next time
+
+// an archive recorded in a marker file
+type markerRecord struct {
+	SHA256  string    `json:"sha256"`
+	Options string    `json:"options"` // the options that affect the output
+	Time    time.Time `json:"time"`
+}
+
+// the records of a destination directory, by the absolute path of the archive
+type markers map[string]markerRecord
+
+// options that do not affect what is extracted or listed
+var outputIgnoredFlags = map[string]bool{
+	"archives-from-0": true, "dest-per-archive": true, "d": true, "marker": true,
+	"q": true, "stats": true, "explain-names": true, "keep-going": true, "threads": true,
+}
+
+// the options given on the command line that affect the output, in a canonical form
+func outputOptions() string {
+	var opts []string
+	flag.Visit(func(f *flag.Flag) {
+		if !outputIgnoredFlags[f.Name] {
+			opts = append(opts, "-"+f.Name+"="+f.Value.String())
+		}
+	})
+	sort.Strings(opts)
+	return strings.Join(opts, " ")
+}
+
+func fileSHA256(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// load the marker file of a directory; a missing file has no records
+func loadMarkers(dir string) (markers, error) {
+	m := make(markers)
+	data, err := os.ReadFile(filepath.Join(dir, markerFilename))
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &m)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Join(dir, markerFilename), err)
+	}
+	return m, nil
+}
+
+func (m markers) save(dir string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	filename := filepath.Join(dir, markerFilename)
+	tmp := filename + ".tmp"
+	err = os.WriteFile(tmp, data, 0666)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
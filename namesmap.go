@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const namesMapFilename = "names.map"
+
+// namesMap records the original raw name, the assumed encoding and the final path of extracted entries
+type namesMap struct {
+	f *os.File
+	w *bufio.Writer
+}
+
+// create a names.map file in the directory
+func createNamesMap(dir string) (m *namesMap, err error) {
+	err = makeDir(dir)
+	if err != nil {
+		return
+	}
+	f, err := createFile(filepath.Join(dir, namesMapFilename))
+	if err != nil {
+		return
+	}
+	m = &namesMap{f: f, w: bufio.NewWriter(f)}
+	_, err = fmt.Fprintf(m.w, "# raw name (hex)\tencoding\tpath\n")
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return
+}
+
+// add a record of an extracted entry, with the path it has after staging
+func (m *namesMap) add(rawName, encoding, path string) (err error) {
+	path = finalPath(path)
+	_, err = fmt.Fprintf(m.w, "%s\t%s\t%s\n", hex.EncodeToString([]byte(rawName)), encoding, path)
+	return
+}
+
+func (m *namesMap) Close() error {
+	err := m.w.Flush()
+	if e := m.f.Close(); err == nil {
+		err = e
+	}
+	return err
+}
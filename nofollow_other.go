@@ -0,0 +1,6 @@
+//go:build !unix
+
+package main
+
+// open flag not to follow a symlink at the last path component
+const oNoFollow = 0
@@ -0,0 +1,8 @@
+//go:build unix
+
+package main
+
+import "syscall"
+
+// open flag not to follow a symlink at the last path component
+const oNoFollow = syscall.O_NOFOLLOW
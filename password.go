@@ -0,0 +1,276 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/mixcode/codepage-unzip/codepagezip"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+var passwordList = "" // try the passwords in this file against the encrypted entries
+
+// WinZip AES encryption
+const (
+	MethodAES = 99     // the compression method of encrypted entries; the real method is in the extra field
+	extraAES  = 0x9901 // the extra field holding the key strength and the real method
+)
+
+// read the candidate passwords, one per line
+func readPasswords(filename string) (list []string, err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if p := strings.TrimSuffix(sc.Text(), "\r"); p != "" {
+			list = append(list, p)
+		}
+	}
+	return list, sc.Err()
+}
+
+// the byte strings to try for a password. Legacy archivers used the codepage of the system,
+// so a non-ASCII password is tried in -f as well as in UTF-8.
+func passwordVariants(p string) [][]byte {
+	v := [][]byte{[]byte(p)}
+	if !isASCII(p) && !isUTF8(convertFrom) && !wantsDetection() {
+		if s, err := codepagezip.ConvertString(p, UTF8, convertFrom); err == nil && s != p {
+			v = append(v, []byte(s))
+		}
+	}
+	return v
+}
+
+// try each password of -password-list against the encrypted entries, and report which ones open them
+func findPasswords(files []*zip.File) error {
+	candidates, err := readPasswords(passwordList)
+	if err != nil {
+		return err
+	}
+	var left []*zip.File
+	for _, f := range files {
+		if f.Flags&FLAG_ENCRYPTED != 0 && f.CompressedSize64 > 0 {
+			left = append(left, f)
+		}
+	}
+	if len(left) == 0 {
+		fmt.Printf("No entries are encrypted\n")
+		return nil
+	}
+
+	total := len(left)
+	for _, p := range candidates {
+		var rest []*zip.File
+		opened := 0
+		for _, f := range left {
+			ok := false
+			for _, pw := range passwordVariants(p) {
+				ok, err = checkPassword(f, pw)
+				if err != nil {
+					return fmt.Errorf("%s: %w", f.Name, err)
+				}
+				if ok {
+					break
+				}
+			}
+			if ok {
+				opened++
+			} else {
+				rest = append(rest, f)
+			}
+		}
+		if opened > 0 {
+			fmt.Printf("password %q opens %d of %d encrypted entries\n", p, opened, total)
+		}
+		left = rest
+		if len(left) == 0 {
+			return nil
+		}
+	}
+	if len(left) == total {
+		return fmt.Errorf("none of the %d passwords opens the encrypted entries", len(candidates))
+	}
+	return fmt.Errorf("no password opens %d of %d encrypted entries", len(left), total)
+}
+
+// check a password against an encrypted entry: by the check byte or the verifier first,
+// and then by the CRC of the decrypted data or the authentication code
+func checkPassword(f *zip.File, password []byte) (bool, error) {
+	r, err := f.OpenRaw()
+	if err != nil {
+		return false, err
+	}
+	if f.Method == MethodAES {
+		return checkAESPassword(f, r, password)
+	}
+	return checkZipCryptoPassword(f, r, password)
+}
+
+// the keys of the traditional PKWARE encryption
+type zipCrypto struct {
+	k0, k1, k2 uint32
+}
+
+func newZipCrypto(password []byte) *zipCrypto {
+	z := &zipCrypto{0x12345678, 0x23456789, 0x34567890}
+	for _, b := range password {
+		z.update(b)
+	}
+	return z
+}
+
+func crc32Byte(crc uint32, b byte) uint32 {
+	return crc32.IEEETable[byte(crc)^b] ^ crc>>8
+}
+
+func (z *zipCrypto) update(b byte) {
+	z.k0 = crc32Byte(z.k0, b)
+	z.k1 = (z.k1+z.k0&0xff)*134775813 + 1
+	z.k2 = crc32Byte(z.k2, byte(z.k1>>24))
+}
+
+func (z *zipCrypto) decrypt(buf []byte) {
+	for i, c := range buf {
+		t := z.k2 | 2
+		buf[i] = c ^ byte(t*(t^1)>>8)
+		z.update(buf[i])
+	}
+}
+
+type zipCryptoReader struct {
+	r io.Reader
+	z *zipCrypto
+}
+
+func (zr *zipCryptoReader) Read(p []byte) (int, error) {
+	n, err := zr.r.Read(p)
+	zr.z.decrypt(p[:n])
+	return n, err
+}
+
+// the decompressor of a method, for checking the CRC of decrypted data; nil if it is not known
+func rawDecompressor(method uint16) func(io.Reader) io.ReadCloser {
+	switch method {
+	case zip.Store:
+		return io.NopCloser
+	case zip.Deflate:
+		return flate.NewReader
+	case MethodZstd:
+		return func(r io.Reader) io.ReadCloser {
+			d, err := zstd.NewReader(r)
+			if err != nil {
+				return errReadCloser{err}
+			}
+			return d.IOReadCloser()
+		}
+	}
+	return nil
+}
+
+// check a password of the traditional PKWARE encryption. The last byte of the 12-byte encryption header
+// is the high byte of the CRC, or of the DOS time with a data descriptor; one wrong password in 256 passes,
+// so the data is decrypted and its CRC checked as well.
+func checkZipCryptoPassword(f *zip.File, r io.Reader, password []byte) (bool, error) {
+	header := make([]byte, 12)
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return false, err
+	}
+	z := newZipCrypto(password)
+	z.decrypt(header)
+	check := byte(f.CRC32 >> 24)
+	if f.Flags&FLAG_DATA_DESCRIPTOR != 0 {
+		check = byte(f.ModifiedTime >> 8)
+	}
+	if header[11] != check {
+		return false, nil
+	}
+
+	decompress := rawDecompressor(f.Method)
+	if decompress == nil {
+		return true, nil // the check byte is all that can be checked
+	}
+	d := decompress(&zipCryptoReader{r, z})
+	defer d.Close()
+	h := crc32.NewIEEE()
+	_, err = io.Copy(h, d)
+	if err != nil {
+		return false, nil // garbage does not decompress
+	}
+	return h.Sum32() == f.CRC32, nil
+}
+
+// check a password of WinZip AES encryption by the password verification value after the salt,
+// and then by the HMAC-SHA1 authentication code of the encrypted data
+func checkAESPassword(f *zip.File, r io.Reader, password []byte) (bool, error) {
+	strength, err := aesStrength(f.Extra)
+	if err != nil {
+		return false, err
+	}
+	keyLen := 8 + 8*strength // 16, 24 or 32 bytes
+	saltLen := keyLen / 2
+	if f.CompressedSize64 < uint64(saltLen+2+10) {
+		return false, zip.ErrFormat
+	}
+	head := make([]byte, saltLen+2)
+	_, err = io.ReadFull(r, head)
+	if err != nil {
+		return false, err
+	}
+	keys := pbkdf2SHA1(password, head[:saltLen], 1000, 2*keyLen+2)
+	if !bytes.Equal(keys[2*keyLen:], head[saltLen:]) {
+		return false, nil
+	}
+
+	mac := hmac.New(sha1.New, keys[keyLen:2*keyLen])
+	_, err = io.CopyN(mac, r, int64(f.CompressedSize64)-int64(saltLen+2+10))
+	if err != nil {
+		return false, err
+	}
+	code := make([]byte, 10)
+	_, err = io.ReadFull(r, code)
+	if err != nil {
+		return false, err
+	}
+	return hmac.Equal(mac.Sum(nil)[:10], code), nil
+}
+
+// the key strength in the WinZip AES extra field: 1, 2 or 3 for 128, 192 or 256 bits
+func aesStrength(extra []byte) (int, error) {
+	for len(extra) >= 4 {
+		id := binary.LittleEndian.Uint16(extra)
+		size := int(binary.LittleEndian.Uint16(extra[2:]))
+		if len(extra) < 4+size {
+			break
+		}
+		if id == extraAES && size >= 7 {
+			s := int(extra[4+4])
+			if s < 1 || s > 3 {
+				break
+			}
+			return s, nil
+		}
+		extra = extra[4+size:]
+	}
+	return 0, errors.New("no valid AES extra field")
+}
+
+// PBKDF2 with HMAC-SHA1, as WinZip AES derives its keys
+func pbkdf2SHA1(password, salt []byte, iter, keyLen int) []byte {
+	return pbkdf2.Key(password, salt, iter, keyLen, sha1.New)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Large entries are read in a pipeline: reading compressed data, decompressing and writing
+// run in their own goroutines, connected with bounded channels.
+
+const (
+	pipelineMinSize = 4 << 20   // entries smaller than this are read directly
+	pipelineChunk   = 256 << 10 // size of a chunk passed between stages
+	pipelineDepth   = 4         // chunks buffered between stages
+)
+
+type readAheadChunk struct {
+	b   []byte
+	err error
+}
+
+// readAheadReader reads from another reader in a goroutine, ahead of the consumer
+type readAheadReader struct {
+	ch        chan readAheadChunk
+	done      chan struct{} // closed to stop the goroutine
+	exited    chan struct{} // closed when the goroutine has returned
+	closeOnce sync.Once
+	cur       []byte
+	err       error
+}
+
+func readAhead(r io.Reader) *readAheadReader {
+	ra := &readAheadReader{ch: make(chan readAheadChunk, pipelineDepth), done: make(chan struct{}), exited: make(chan struct{})}
+	go func() {
+		defer handleCrash()
+		defer close(ra.exited)
+		defer close(ra.ch)
+		for {
+			b := make([]byte, pipelineChunk)
+			n := 0
+			var err error
+			for n < len(b) && err == nil {
+				var m int
+				m, err = r.Read(b[n:])
+				n += m
+			}
+			select {
+			case ra.ch <- readAheadChunk{b[:n], err}:
+			case <-ra.done:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return ra
+}
+
+func (ra *readAheadReader) Read(p []byte) (int, error) {
+	for len(ra.cur) == 0 {
+		if ra.err != nil {
+			return 0, ra.err
+		}
+		select {
+		case c, ok := <-ra.ch:
+			if !ok {
+				return 0, io.EOF
+			}
+			ra.cur, ra.err = c.b, c.err
+		case <-ra.done:
+			return 0, errReadAheadClosed
+		}
+	}
+	n := copy(p, ra.cur)
+	ra.cur = ra.cur[n:]
+	return n, nil
+}
+
+var errReadAheadClosed = errors.New("read from a closed reader")
+
+// tell the reading goroutine to stop
+func (ra *readAheadReader) stop() {
+	ra.closeOnce.Do(func() { close(ra.done) })
+}
+
+// stop the reading goroutine, and wait for it to return
+func (ra *readAheadReader) Close() error {
+	ra.stop()
+	<-ra.exited
+	return nil
+}
+
+// pipelinedEntry is the decompressed data of an entry read in a pipeline
+type pipelinedEntry struct {
+	raw, out *readAheadReader
+	dec      io.ReadCloser
+}
+
+func (p *pipelinedEntry) Read(b []byte) (int, error) {
+	return p.out.Read(b)
+}
+
+// crcReader checks the CRC-32 of the data of an entry at its end.
+// archive/zip does not check it when the recorded CRC is zero, but only empty data has that CRC
+// if nothing else is wrong; a corrupted header or stream is found when any data is read.
+type crcReader struct {
+	io.ReadCloser
+	hash hash.Hash32
+	crc  uint32
+}
+
+func (c *crcReader) Read(b []byte) (n int, err error) {
+	n, err = c.ReadCloser.Read(b)
+	c.hash.Write(b[:n])
+	if err == io.EOF && c.hash.Sum32() != c.crc {
+		err = zip.ErrChecksum
+	}
+	return
+}
+
+// stop both stages, and close the decompressor only when no goroutine reads it
+func (p *pipelinedEntry) Close() error {
+	p.out.stop()
+	p.raw.stop() // unblocks the decompressor of the out stage waiting for raw data
+	p.out.Close()
+	p.raw.Close()
+	if p.dec != nil {
+		p.dec.Close()
+	}
+	return nil
+}
+
+// open an entry for reading, in a pipeline if it is large
+func openEntry(entry *zip.File) (io.ReadCloser, error) {
+	// archive/zip does not read the data of an entry named like a directory, but the raw data can be read
+	dirName := strings.HasSuffix(entry.Name, "/")
+	if entry.Flags&FLAG_ENCRYPTED != 0 { // AE-2 records no CRC
+		return entry.Open()
+	}
+	if (entry.UncompressedSize64 < pipelineMinSize && !dirName) || (entry.Method != zip.Store && entry.Method != zip.Deflate) {
+		rc, err := entry.Open()
+		if err != nil || entry.CRC32 != 0 { // archive/zip checks a CRC that is not zero
+			return rc, err
+		}
+		return &crcReader{ReadCloser: rc, hash: crc32.NewIEEE()}, nil
+	}
+	rr, err := entry.OpenRaw()
+	if err != nil {
+		return nil, err
+	}
+	p := &pipelinedEntry{raw: readAhead(rr)}
+	var data io.Reader = p.raw
+	if entry.Method == zip.Deflate {
+		p.dec = flate.NewReader(p.raw)
+		data = p.dec
+	}
+	p.out = readAhead(data)
+	return &crcReader{ReadCloser: p, hash: crc32.NewIEEE(), crc: entry.CRC32}, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"hash/crc32"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// entries whose data does not match a recorded CRC of zero are found, small or read in a pipeline
+func TestOpenEntryCRC(t *testing.T) {
+	small := []byte("some data")
+	large := bytes.Repeat([]byte("0123456789abcdef"), pipelineMinSize/16+1)
+	tests := []struct {
+		name    string
+		content []byte
+		crc     uint32
+		want    error
+	}{
+		{"empty", nil, 0, nil},
+		{"small", small, crc32.ChecksumIEEE(small), nil},
+		{"small, zero CRC", small, 0, zip.ErrChecksum},
+		{"large", large, crc32.ChecksumIEEE(large), nil},
+		{"large, zero CRC", large, 0, zip.ErrChecksum},
+		{"large, wrong CRC", large, 1, zip.ErrChecksum},
+	}
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for _, tt := range tests {
+		fh := &zip.FileHeader{Name: tt.name, Method: zip.Store, CRC32: tt.crc,
+			CompressedSize64: uint64(len(tt.content)), UncompressedSize64: uint64(len(tt.content))}
+		w, err := zw.CreateRaw(fh)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(tt.content)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tt := range tests {
+		rc, err := openEntry(zr.File[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = io.Copy(io.Discard, rc)
+		rc.Close()
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+// copyEntry stops at data longer than declared, and at a stream that stalls longer than -entry-timeout
+func TestCopyEntry(t *testing.T) {
+	defer func(d time.Duration) { entryTimeout = d }(entryTimeout)
+	stalled, w := io.Pipe()
+	defer w.Close()
+
+	tests := []struct {
+		name    string
+		r       io.Reader
+		size    uint64
+		timeout time.Duration
+		fails   bool
+		copied  int64 // at most
+	}{
+		{"exact", strings.NewReader("0123456789"), 10, 0, false, 10},
+		{"short", strings.NewReader("01234"), 10, 0, false, 5},
+		{"long", strings.NewReader("0123456789"), 5, 0, true, 6},
+		{"long with a timeout", strings.NewReader("0123456789"), 5, time.Second, true, 6},
+		{"stalled", stalled, 10, 20 * time.Millisecond, true, 0},
+	}
+	for _, tt := range tests {
+		entryTimeout = tt.timeout
+		n, err := copyEntry(io.Discard, tt.r, tt.size)
+		if (err != nil) != tt.fails {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if n > tt.copied {
+			t.Errorf("%s: %d bytes copied, want at most %d", tt.name, n, tt.copied)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// reserve disk space for a file being written with F_PREALLOCATE, which allocates blocks without extending the file
+func preallocateFile(f *os.File, size int64) error {
+	if size == 0 {
+		return nil
+	}
+	err := unix.FcntlFstore(f.Fd(), unix.F_PREALLOCATE, &unix.Fstore_t{
+		Flags:   unix.F_ALLOCATEALL,
+		Posmode: unix.F_PEOFPOSMODE,
+		Length:  size,
+	})
+	if err == unix.ENOTSUP {
+		// not supported by the filesystem
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,31 @@
+//go:build freebsd && (amd64 || arm64 || riscv64)
+
+package main
+
+import (
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// reserve disk space for a file being written with posix_fallocate()
+func preallocateFile(f *os.File, size int64) error {
+	if size == 0 {
+		return nil
+	}
+	// the system call returns the error number instead of setting errno
+	r, _, e := unix.Syscall(unix.SYS_POSIX_FALLOCATE, f.Fd(), 0, uintptr(size))
+	if e != 0 {
+		return e
+	}
+	switch err := syscall.Errno(r); err {
+	case 0:
+		return nil
+	case syscall.EINVAL, syscall.EOPNOTSUPP:
+		// not supported by the filesystem, as on ZFS
+		return nil
+	default:
+		return err
+	}
+}
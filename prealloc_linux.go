@@ -0,0 +1,19 @@
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// reserve disk space for a file being written, so that running out of space fails before writing
+func preallocateFile(f *os.File, size int64) error {
+	if size == 0 {
+		return nil
+	}
+	err := syscall.Fallocate(int(f.Fd()), 0, 0, size)
+	if err == syscall.EOPNOTSUPP {
+		// not supported by the filesystem
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !windows && !(freebsd && (amd64 || arm64 || riscv64))
+
+package main
+
+import "os"
+
+// preallocation is not supported here; extending the file would only make it sparse
+func preallocateFile(f *os.File, size int64) error {
+	return nil
+}
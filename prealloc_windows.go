@@ -0,0 +1,11 @@
+package main
+
+import "os"
+
+// reserve disk space for a file being written by extending it, which allocates the clusters on Windows
+func preallocateFile(f *os.File, size int64) error {
+	if size == 0 {
+		return nil
+	}
+	return f.Truncate(size)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var presetName = "" // -preset
+
+// flag values of naming presets
+var presets = map[string]map[string]string{
+	// release names full of full-width letters and invisible characters
+	"anime": {"width-fold": "true", "strip-controls": "true"},
+	// scanned books, often shared between Windows and other systems
+	"scan": {"width-fold": "true", "strip-controls": "true", "windows-names": "true", "fs-names": FsNamesFix},
+	// documents; keep names as they are but make them safe to share
+	"docs": {"strip-controls": "true", "windows-names": "true"},
+	// names exactly as converted
+	"raw": {"width-fold": "false", "strip-controls": "false", "ascii-slugs": "false", "fs-names": FsNamesOff},
+}
+
+func presetNames() string {
+	names := make([]string, 0, len(presets))
+	for k := range presets {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// set the flags of a preset. Flags given on the command line take precedence.
+func applyPreset(name string) error {
+	if name == "" {
+		return nil
+	}
+	p, ok := presets[name]
+	if !ok {
+		return fmt.Errorf("unknown preset '%s' (available: %s)", name, presetNames())
+	}
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+	for k, v := range p {
+		if given[k] {
+			continue
+		}
+		err := flag.Set(k, v)
+		if err != nil {
+			return fmt.Errorf("preset %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var cacheDir = "" // keep the byte ranges read from archives given as URLs in this directory
+
+const (
+	remoteBlockSize = 1 << 20 // the unit of reading and caching a remote archive
+	remoteMemBlocks = 16      // blocks kept in memory
+)
+
+// check if an archive is given as an http or https URL
+func isURL(name string) bool {
+	return strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://")
+}
+
+// options that need the archive as a local file
+func checkRemoteOptions() error {
+	for _, o := range []struct {
+		set  bool
+		name string
+	}{
+		{listCache, "-list-cache"},
+		{zeroCopy, "-zero-copy"},
+		{checkpointFile != "", "-checkpoint"},
+	} {
+		if o.set {
+			return fmt.Errorf("%s is not supported for archives given as URLs", o.name)
+		}
+	}
+	return nil
+}
+
+// A remoteFile reads an archive over HTTP with range requests, in blocks that are kept
+// in memory while it is read, and in the -cache-dir directory, by URL and ETag, for the next run.
+type remoteFile struct {
+	url     string
+	size    int64
+	version string // the ETag, or else the Last-Modified time, for If-Range; empty if neither is given
+	dir     string // the cache directory of this version; empty for no caching on disk
+
+	mu     sync.Mutex
+	blocks map[int64][]byte // blocks in memory
+	order  []int64          // the blocks in memory in the order they were read, for evicting the oldest
+}
+
+// open an archive at a URL, checking that the server supports range requests
+func openRemote(u string) (*remoteFile, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", "bytes=0-0")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("%s: the server does not support range requests", u)
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		return nil, fmt.Errorf("%s: %s", u, resp.Status)
+	}
+	_, total, ok := strings.Cut(resp.Header.Get("Content-Range"), "/")
+	size, err := strconv.ParseInt(total, 10, 64)
+	if !ok || err != nil {
+		return nil, fmt.Errorf("%s: no size in the Content-Range %q", u, resp.Header.Get("Content-Range"))
+	}
+
+	r := &remoteFile{url: u, size: size, blocks: make(map[int64][]byte)}
+	r.version = resp.Header.Get("ETag")
+	if r.version == "" {
+		r.version = resp.Header.Get("Last-Modified")
+	}
+	if cacheDir != "" {
+		if r.version == "" {
+			fmt.Fprintf(os.Stderr, "Warning: %s has no ETag or Last-Modified time; it is not cached\n", u)
+		} else {
+			err = r.openCache()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return r, nil
+}
+
+// choose the cache directory of the URL and version, removing those of other versions of the URL
+func (r *remoteFile) openCache() error {
+	key := func(s string) string {
+		sum := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(sum[:12])
+	}
+	urlDir := filepath.Join(cacheDir, key(r.url))
+	r.dir = filepath.Join(urlDir, key(fmt.Sprintf("%s\n%d", r.version, r.size)))
+	entries, err := os.ReadDir(urlDir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, e := range entries {
+		if e.Name() != filepath.Base(r.dir) {
+			os.RemoveAll(filepath.Join(urlDir, e.Name()))
+		}
+	}
+	return os.MkdirAll(r.dir, fs.ModePerm)
+}
+
+func (r *remoteFile) Size() int64 {
+	return r.size
+}
+
+// the length of a block, which is shorter at the end of the file
+func (r *remoteFile) blockLen(b int64) int64 {
+	return min(remoteBlockSize, r.size-b*remoteBlockSize)
+}
+
+func (r *remoteFile) blockFile(b int64) string {
+	return filepath.Join(r.dir, fmt.Sprintf("%08d", b))
+}
+
+// get a block from memory or the cache directory; nil if it has not been read
+func (r *remoteFile) cached(b int64) []byte {
+	if data, ok := r.blocks[b]; ok {
+		return data
+	}
+	if r.dir == "" {
+		return nil
+	}
+	data, err := os.ReadFile(r.blockFile(b))
+	if err != nil || int64(len(data)) != r.blockLen(b) {
+		return nil
+	}
+	r.keep(b, data)
+	return data
+}
+
+// keep a block in memory, evicting the oldest
+func (r *remoteFile) keep(b int64, data []byte) {
+	if len(r.order) >= remoteMemBlocks {
+		delete(r.blocks, r.order[0])
+		r.order = r.order[1:]
+	}
+	r.blocks[b] = data
+	r.order = append(r.order, b)
+}
+
+// read the blocks first to last from the server in one request, and cache them
+func (r *remoteFile) fetch(first, last int64) error {
+	req, err := http.NewRequest(http.MethodGet, r.url, nil)
+	if err != nil {
+		return err
+	}
+	end := last*remoteBlockSize + r.blockLen(last) - 1
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", first*remoteBlockSize, end))
+	if r.version != "" {
+		req.Header.Set("If-Range", r.version)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return fmt.Errorf("%s changed on the server while it was read", r.url)
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("%s: %s", r.url, resp.Status)
+	}
+	for b := first; b <= last; b++ {
+		data := make([]byte, r.blockLen(b))
+		_, err = io.ReadFull(resp.Body, data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", r.url, err)
+		}
+		r.keep(b, data)
+		if r.dir != "" {
+			tmp := r.blockFile(b) + ".tmp"
+			err = os.WriteFile(tmp, data, 0666)
+			if err == nil {
+				err = os.Rename(tmp, r.blockFile(b))
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ReadAt reads from the blocks covering the range, reading those not cached from the server,
+// each run of them in one request
+func (r *remoteFile) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	end := min(off+int64(len(p)), r.size)
+	first, last := off/remoteBlockSize, (end-1)/remoteBlockSize
+	for b := first; b <= last; b++ {
+		if r.cached(b) != nil {
+			continue
+		}
+		run := b
+		for run < last && r.cached(run+1) == nil {
+			run++
+		}
+		err = r.fetch(b, run)
+		if err != nil {
+			return 0, err
+		}
+		b = run
+	}
+	for b := first; b <= last; b++ {
+		data := r.cached(b)
+		if data == nil { // evicted by a read of more blocks than are kept in memory
+			err = r.fetch(b, b)
+			if err != nil {
+				return n, err
+			}
+			data = r.blocks[b]
+		}
+		start := max(off-b*remoteBlockSize, 0)
+		n += copy(p[n:], data[start:])
+	}
+	if n < len(p) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// the name of an archive given as a URL, for -k
+func remoteBaseName(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.Path == "" {
+		return "archive"
+	}
+	return parsed.Path[strings.LastIndex(parsed.Path, "/")+1:]
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRemoteFile(t *testing.T) {
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	content := make([]byte, 3*remoteBlockSize+1234)
+	rand.New(rand.NewSource(1)).Read(content)
+	for _, name := range []string{"a.bin", "b.bin"} {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(content)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	archive := buf.Bytes()
+
+	etag := `"v1"`
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "t.zip", time.Time{}, bytes.NewReader(archive))
+	}))
+	defer srv.Close()
+	defer func(d string) { cacheDir = d }(cacheDir)
+	cacheDir = t.TempDir()
+
+	// read the archive, and count the requests besides the first one checking it
+	read := func() int {
+		requests.Store(0)
+		rf, err := openRemote(srv.URL + "/t.zip")
+		if err != nil {
+			t.Fatal(err)
+		}
+		zr, err := zip.NewReader(rf, rf.Size())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil || !bytes.Equal(b, content) {
+				t.Fatalf("%s: read %d bytes, %v", f.Name, len(b), err)
+			}
+		}
+		return int(requests.Load()) - 1
+	}
+	if n := read(); n == 0 || n > 8 {
+		t.Errorf("%d requests for %d blocks", n, len(archive)/remoteBlockSize+1)
+	}
+	if n := read(); n != 0 {
+		t.Errorf("%d requests with everything cached", n)
+	}
+	etag = `"v2"`
+	if n := read(); n == 0 {
+		t.Error("the cache of another version was used")
+	}
+
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(archive)
+	}))
+	defer plain.Close()
+	if _, err := openRemote(plain.URL); err == nil || !strings.Contains(err.Error(), "range") {
+		t.Errorf("a server without range requests: %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/mixcode/codepage-unzip/codepagezip"
+)
+
+var (
+	routeSpec = ""                      // -route flag
+	routes    = make(map[string]string) // lowercase extension without a dot -> subdirectory
+)
+
+// parse a route spec like "jpg,png=images/;txt=docs/"
+func parseRoutes(spec string) error {
+	for _, rule := range strings.Split(spec, ";") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		exts, dir, ok := strings.Cut(rule, "=")
+		if !ok {
+			return fmt.Errorf("invalid route '%s': must be like ext,ext=dir/", rule)
+		}
+		dir = codepagezip.SanitizePath(dir)
+		if dir == "" {
+			return fmt.Errorf("invalid route '%s': empty directory", rule)
+		}
+		for _, ext := range strings.Split(exts, ",") {
+			ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+			if ext == "" {
+				return fmt.Errorf("invalid route '%s': empty extension", rule)
+			}
+			routes[ext] = dir
+		}
+	}
+	return nil
+}
+
+// get the subdirectory of the output directory to put a file entry into
+func routeDir(name string) string {
+	if len(routes) == 0 || strings.HasSuffix(name, "/") || strings.HasSuffix(name, "\\") {
+		return ""
+	}
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(codepagezip.SanitizePath(name)), "."))
+	return routes[ext]
+}
@@ -0,0 +1,226 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// sandbox confines file creation to a directory using openat2() with RESOLVE_BENEATH,
+// so that no path resolution, including symlinks, can escape the directory.
+// Other operations work on a parent directory opened the same way, and do not follow the last component.
+type sandbox struct {
+	root    int    // descriptor of the root directory
+	rootDir string // the root directory path
+}
+
+// open a path beneath a directory descriptor; openat2() is available since Linux 5.6
+func openBeneath(dirfd int, path string, flags int, mode uint32) (int, error) {
+	how := &unix.OpenHow{
+		Flags:   uint64(flags | unix.O_CLOEXEC),
+		Mode:    uint64(mode),
+		Resolve: unix.RESOLVE_BENEATH | unix.RESOLVE_NO_MAGICLINKS,
+	}
+	for {
+		fd, err := unix.Openat2(dirfd, path, how)
+		if err == unix.EINTR || err == unix.EAGAIN {
+			continue
+		}
+		return fd, err
+	}
+}
+
+func openSandbox(dir string) (*sandbox, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	fd, err := unix.Open(dir, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: dir, Err: err}
+	}
+	// check that the kernel supports openat2()
+	test, err := openBeneath(fd, ".", unix.O_RDONLY|unix.O_DIRECTORY, 0)
+	if err != nil {
+		unix.Close(fd)
+		if err == unix.ENOSYS {
+			return nil, fmt.Errorf("sandbox mode needs openat2(), which is not supported by this kernel")
+		}
+		return nil, err
+	}
+	unix.Close(test)
+	return &sandbox{root: fd, rootDir: dir}, nil
+}
+
+func (s *sandbox) Close() error {
+	return unix.Close(s.root)
+}
+
+// get a path relative to the root directory
+func (s *sandbox) rel(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if !isBeneath(s.rootDir, abs) {
+		return "", fmt.Errorf("%s is outside of the sandbox %s", path, s.rootDir)
+	}
+	return filepath.Rel(s.rootDir, abs)
+}
+
+// open the parent directory of a path under the root, and get the last component
+func (s *sandbox) parent(op, path string) (dirfd int, base string, err error) {
+	rel, err := s.rel(path)
+	if err != nil {
+		return -1, "", err
+	}
+	if rel == "." {
+		return -1, "", &os.PathError{Op: op, Path: path, Err: unix.EINVAL}
+	}
+	dirfd, err = openBeneath(s.root, filepath.Dir(rel), unix.O_RDONLY|unix.O_DIRECTORY, 0)
+	if err != nil {
+		return -1, "", &os.PathError{Op: op, Path: path, Err: err}
+	}
+	return dirfd, filepath.Base(rel), nil
+}
+
+// create a directory and its parents under the root
+func (s *sandbox) MkdirAll(path string) error {
+	rel, err := s.rel(path)
+	if err != nil {
+		return err
+	}
+	dirfd, err := unix.Dup(s.root)
+	if err != nil {
+		return err
+	}
+	defer func() { unix.Close(dirfd) }()
+	for _, c := range strings.Split(rel, string(filepath.Separator)) {
+		if c == "." || c == "" {
+			continue
+		}
+		err = unix.Mkdirat(dirfd, c, 0777)
+		if err != nil && err != unix.EEXIST {
+			return &os.PathError{Op: "mkdir", Path: path, Err: err}
+		}
+		fd, err := openBeneath(dirfd, c, unix.O_RDONLY|unix.O_DIRECTORY, 0)
+		if err != nil {
+			return &os.PathError{Op: "open", Path: path, Err: err}
+		}
+		unix.Close(dirfd)
+		dirfd = fd
+	}
+	return nil
+}
+
+func (s *sandbox) open(path string, flags int, perm os.FileMode) (*os.File, error) {
+	rel, err := s.rel(path)
+	if err != nil {
+		return nil, err
+	}
+	fd, err := openBeneath(s.root, rel, flags, uint32(perm.Perm()))
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	return os.NewFile(uintptr(fd), path), nil
+}
+
+// create or truncate a file under the root
+func (s *sandbox) Create(path string) (*os.File, error) {
+	return s.open(path, unix.O_WRONLY|unix.O_CREAT|unix.O_TRUNC, 0666)
+}
+
+// create a new file under the root, failing if it exists
+func (s *sandbox) CreateNew(path string, perm os.FileMode) (*os.File, error) {
+	return s.open(path, unix.O_WRONLY|unix.O_CREAT|unix.O_EXCL, perm)
+}
+
+// make a symlink under the root; the target is not resolved, and opening through the link stays confined
+func (s *sandbox) Symlink(target, path string) error {
+	dirfd, base, err := s.parent("symlink", path)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(dirfd)
+	err = unix.Symlinkat(target, dirfd, base)
+	if err != nil {
+		return &os.LinkError{Op: "symlink", Old: target, New: path, Err: err}
+	}
+	return nil
+}
+
+// make a hard link between two paths under the root, without following a symlink at oldpath
+func (s *sandbox) Link(oldpath, newpath string) error {
+	olddirfd, oldbase, err := s.parent("link", oldpath)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(olddirfd)
+	newdirfd, newbase, err := s.parent("link", newpath)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(newdirfd)
+	err = unix.Linkat(olddirfd, oldbase, newdirfd, newbase, 0)
+	if err != nil {
+		return &os.LinkError{Op: "link", Old: oldpath, New: newpath, Err: err}
+	}
+	return nil
+}
+
+// rename a path under the root to another
+func (s *sandbox) Rename(oldpath, newpath string) error {
+	olddirfd, oldbase, err := s.parent("rename", oldpath)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(olddirfd)
+	newdirfd, newbase, err := s.parent("rename", newpath)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(newdirfd)
+	err = unix.Renameat(olddirfd, oldbase, newdirfd, newbase)
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: err}
+	}
+	return nil
+}
+
+// remove a file or an empty directory under the root
+func (s *sandbox) Remove(path string) error {
+	dirfd, base, err := s.parent("remove", path)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(dirfd)
+	err = unix.Unlinkat(dirfd, base, 0)
+	if err == unix.EISDIR {
+		err = unix.Unlinkat(dirfd, base, unix.AT_REMOVEDIR)
+	}
+	if err != nil {
+		return &os.PathError{Op: "remove", Path: path, Err: err}
+	}
+	return nil
+}
+
+// set the access and modification times of a path under the root, without following a symlink
+func (s *sandbox) Chtimes(path string, atime, mtime time.Time) error {
+	dirfd, base, err := s.parent("chtimes", path)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(dirfd)
+	ts := []unix.Timespec{unix.NsecToTimespec(atime.UnixNano()), unix.NsecToTimespec(mtime.UnixNano())}
+	err = unix.UtimesNanoAt(dirfd, base, ts, unix.AT_SYMLINK_NOFOLLOW)
+	if err != nil {
+		return &os.PathError{Op: "chtimes", Path: path, Err: err}
+	}
+	return nil
+}
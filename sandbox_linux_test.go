@@ -0,0 +1,140 @@
+//go:build linux
+
+package main
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSandbox(t *testing.T) {
+	tmp := t.TempDir()
+	root := filepath.Join(tmp, "out")
+	outside := filepath.Join(tmp, "outside")
+	for _, d := range []string{root, outside} {
+		if err := os.Mkdir(d, 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err := openSandbox(root)
+	if err != nil {
+		t.Skip("openat2() is not available:", err)
+	}
+	defer s.Close()
+
+	// an archive may make a link leading out, and then write through it
+	if err := s.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Symlink("../outside", filepath.Join(root, "up")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.MkdirAll(filepath.Join(root, "a", "b")); err != nil {
+		t.Fatal(err)
+	}
+	f, err := s.Create(filepath.Join(root, "a", "b", "f"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if err := s.Link(filepath.Join(root, "a", "b", "f"), filepath.Join(root, "g")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Rename(filepath.Join(root, "g"), filepath.Join(root, "a", "g")); err != nil {
+		t.Fatal(err)
+	}
+
+	refused := map[string]func() error{
+		"create through a link": func() error {
+			f, err := s.Create(filepath.Join(root, "escape", "f"))
+			if err == nil {
+				f.Close()
+			}
+			return err
+		},
+		"create through a relative link": func() error {
+			f, err := s.CreateNew(filepath.Join(root, "up", "f"), 0666)
+			if err == nil {
+				f.Close()
+			}
+			return err
+		},
+		"create outside":       func() error { _, err := s.Create(filepath.Join(outside, "f")); return err },
+		"mkdir through a link": func() error { return s.MkdirAll(filepath.Join(root, "escape", "d")) },
+		"symlink through a link": func() error {
+			return s.Symlink("x", filepath.Join(root, "escape", "l"))
+		},
+		"link outside":   func() error { return s.Link(filepath.Join(root, "a", "g"), filepath.Join(outside, "g")) },
+		"rename outside": func() error { return s.Rename(filepath.Join(root, "a", "g"), filepath.Join(root, "up", "g")) },
+		"remove through a link": func() error {
+			return s.Remove(filepath.Join(root, "escape", "x"))
+		},
+		"chtimes outside": func() error {
+			return s.Chtimes(filepath.Join(root, "..", "outside"), time.Now(), time.Now())
+		},
+	}
+	if err := os.WriteFile(filepath.Join(outside, "x"), nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+	for name, f := range refused {
+		if err := f(); err == nil {
+			t.Errorf("%s: not refused", name)
+		}
+	}
+	entries, err := os.ReadDir(outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "x" {
+		t.Errorf("the outside directory was written to: %v", entries)
+	}
+
+	// the last component is not followed
+	if err := s.Chtimes(filepath.Join(root, "escape"), time.Unix(0, 0), time.Unix(0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if st, err := os.Stat(outside); err != nil || st.ModTime().Unix() == 0 {
+		t.Errorf("the link target was changed")
+	}
+	if err := s.Remove(filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(outside); err != nil {
+		t.Errorf("the link target was removed")
+	}
+}
+
+// in sandbox mode, devices, FIFOs, sockets and setuid or setgid files are refused before anything is written
+func TestSandboxModes(t *testing.T) {
+	root := t.TempDir()
+	s, err := openSandbox(root)
+	if err != nil {
+		t.Skip("openat2() is not available:", err)
+	}
+	defer s.Close()
+	defer func(b *sandbox, d string) { box, destDir = b, d }(box, destDir)
+	box, destDir = s, root
+
+	modes := []os.FileMode{
+		os.ModeDevice | 0600,
+		os.ModeDevice | os.ModeCharDevice | 0600,
+		os.ModeNamedPipe | 0644,
+		os.ModeSocket | 0755,
+		os.ModeSetuid | 0755,
+		os.ModeSetgid | 0755,
+		os.ModeSetuid | os.ModeSetgid | os.ModeDir | 0755,
+	}
+	for _, mode := range modes {
+		fh := &zip.FileHeader{Name: "f"}
+		fh.SetMode(mode)
+		if err := writeFile(&zip.File{FileHeader: *fh}, "f"); err == nil {
+			t.Errorf("mode %v was not refused", mode)
+		}
+	}
+	if entries, err := os.ReadDir(root); err != nil || len(entries) != 0 {
+		t.Errorf("written to the sandbox: %v, %v", entries, err)
+	}
+}
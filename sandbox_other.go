@@ -0,0 +1,25 @@
+//go:build !linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+type sandbox struct{}
+
+func openSandbox(dir string) (*sandbox, error) {
+	return nil, fmt.Errorf("sandbox mode is supported only on Linux")
+}
+
+func (s *sandbox) Close() error                                              { return nil }
+func (s *sandbox) MkdirAll(path string) error                                { return nil }
+func (s *sandbox) Create(path string) (*os.File, error)                      { return nil, nil }
+func (s *sandbox) CreateNew(path string, perm os.FileMode) (*os.File, error) { return nil, nil }
+func (s *sandbox) Symlink(target, path string) error                         { return nil }
+func (s *sandbox) Link(oldpath, newpath string) error                        { return nil }
+func (s *sandbox) Rename(oldpath, newpath string) error                      { return nil }
+func (s *sandbox) Remove(path string) error                                  { return nil }
+func (s *sandbox) Chtimes(path string, atime, mtime time.Time) error         { return nil }
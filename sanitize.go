@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mixcode/codepage-unzip/codepagezip"
+)
+
+// check if a path is the root or under the root
+func isBeneath(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// verify that no existing component of dir under root is a symbolic link leading outside of root.
+// An archive may make such a link and then write files through it.
+func checkDirBeneath(root, dir string) error {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return err
+	}
+	if rel == "." {
+		return nil
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if os.IsNotExist(err) {
+		// nothing exists yet
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	p := root
+	for _, c := range strings.Split(rel, string(filepath.Separator)) {
+		p = filepath.Join(p, c)
+		st, err := os.Lstat(p)
+		if os.IsNotExist(err) {
+			// the rest of the path will be created
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if st.Mode()&fs.ModeSymlink == 0 {
+			continue
+		}
+		real, err := filepath.EvalSymlinks(p)
+		if err != nil {
+			return err
+		}
+		if !isBeneath(realRoot, real) {
+			return fmt.Errorf("%s is a symbolic link to outside of the output directory", p)
+		}
+	}
+	return nil
+}
+
+// get the number of path components of an entry name
+func pathDepth(name string) int {
+	p := codepagezip.SanitizePath(name)
+	if p == "" {
+		return 0
+	}
+	return strings.Count(p, "/") + 1
+}
+
+var stripControls = false // remove control, bidi and zero-width characters from names
+
+// report whether a rune is invisible or changes how the text around it is displayed
+func isControlLike(r rune) bool {
+	switch {
+	case r < 0x20 || 0x7f <= r && r < 0xa0: // C0, DEL and C1 controls
+		return true
+	case r == 0x061c || r == 0x200e || r == 0x200f: // bidi marks
+		return true
+	case 0x202a <= r && r <= 0x202e || 0x2066 <= r && r <= 0x2069: // bidi embeddings, overrides and isolates
+		return true
+	case 0x200b <= r && r <= 0x200d || r == 0x2060 || r == 0xfeff: // zero-width characters
+		return true
+	}
+	return false
+}
+
+// remove control-like characters from each component of a slash-separated name
+func stripControlChars(name string) string {
+	comp := strings.Split(name, "/")
+	for i, c := range comp {
+		s := strings.Map(func(r rune) rune {
+			if isControlLike(r) {
+				return -1
+			}
+			return r
+		}, c)
+		if s == "" && c != "" {
+			s = "_"
+		}
+		comp[i] = s
+	}
+	return strings.Join(comp, "/")
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsBeneath(t *testing.T) {
+	root := filepath.FromSlash("/out")
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/out", true},
+		{"/out/a/b", true},
+		{"/out/..a", true},
+		{"/out/../x", false},
+		{"/outside", false},
+		{"/", false},
+	}
+	for _, tt := range tests {
+		if got := isBeneath(root, filepath.FromSlash(tt.path)); got != tt.want {
+			t.Errorf("isBeneath(%s, %s) = %v, want %v", root, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestCheckDirBeneath(t *testing.T) {
+	tmp := t.TempDir()
+	root := filepath.Join(tmp, "out")
+	if err := os.MkdirAll(filepath.Join(root, "dir"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(tmp, filepath.Join(root, "escape")); err != nil {
+		t.Skip("cannot make symbolic links:", err)
+	}
+	if err := os.Symlink("dir", filepath.Join(root, "inside")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dir := range []string{"dir", "dir/new/deeper", "inside", "inside/new", "new"} {
+		if err := checkDirBeneath(root, filepath.Join(root, filepath.FromSlash(dir))); err != nil {
+			t.Errorf("%s: %v", dir, err)
+		}
+	}
+	for _, dir := range []string{"escape", "escape/out", "escape/new"} {
+		if err := checkDirBeneath(root, filepath.Join(root, filepath.FromSlash(dir))); err == nil {
+			t.Errorf("%s leads outside of the output directory, but was accepted", dir)
+		}
+	}
+}
+
+func TestResolveLinkTarget(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "out")
+	if err := os.MkdirAll(filepath.Join(root, "d"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(".", filepath.Join(root, "l1")); err != nil {
+		t.Skip("cannot make symbolic links:", err)
+	}
+	saved := destDir
+	destDir = root
+	defer func() { destDir = saved }()
+
+	link := func(name, target string) pendingLink {
+		return pendingLink{name: name, outpath: filepath.Join(root, filepath.FromSlash(name)), target: filepath.FromSlash(target)}
+	}
+	for _, l := range []pendingLink{link("a", "d"), link("d/b", "../d"), link("d/c", "../l1/d")} {
+		if _, err := resolveLinkTarget(l); err != nil {
+			t.Errorf("%s -> %s: %v", l.name, l.target, err)
+		}
+	}
+	// l1 -> . makes l1/l2 -> ../x a link to the parent of the output directory
+	for _, l := range []pendingLink{link("l1/l2", "../escaped"), link("d/e", "../../x"), link("f", "d/../../x")} {
+		if _, err := resolveLinkTarget(l); err == nil {
+			t.Errorf("%s -> %s was accepted", l.name, l.target)
+		}
+	}
+}
+
+func TestCopyTree(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "out")
+	if err := os.MkdirAll(filepath.Join(root, "d", "sub"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "d", "sub", "f"), []byte("data"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := copyTree(filepath.Join(root, "copy"), filepath.Join(root, "d")); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(filepath.Join(root, "copy", "sub", "f")); err != nil || string(b) != "data" {
+		t.Errorf("copy/sub/f: %q, %v", b, err)
+	}
+
+	// d/self -> . and d/sub/up -> .. copy d into itself
+	for _, dst := range []string{"d/self", "d/sub/up", "d"} {
+		if err := copyTree(filepath.Join(root, filepath.FromSlash(dst)), filepath.Join(root, "d")); err == nil {
+			t.Errorf("copying d to %s was accepted", dst)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "d", "self")); !os.IsNotExist(err) {
+		t.Errorf("d/self was made: %v", err)
+	}
+}
+
+// the subdirectory of -k is sanitized like the components of entry names
+func TestKeepDirName(t *testing.T) {
+	tests := []struct {
+		archive, want string
+	}{
+		{"a.zip", "a"},
+		{filepath.Join("dir", "photos.2001.zip"), "photos.2001"},
+		{"noext", "noext"},
+		{"..zip", "_"},
+		{"a\x00b\x1f.zip", "a_b_"},
+		{"https://example.com/files/data.zip?x=1", "data"},
+		{"https://example.com", "archive"},
+	}
+	for _, tt := range tests {
+		if got := keepDirName(tt.archive); got != tt.want {
+			t.Errorf("keepDirName(%q) = %q, want %q", tt.archive, got, tt.want)
+		}
+	}
+}
+
+func TestKeepDirPath(t *testing.T) {
+	defer func(p string, q bool) { keepDirPolicy, quiet = p, q }(keepDirPolicy, quiet)
+	quiet = true
+	tmp := t.TempDir()
+	for _, d := range []string{"empty", "full", "full-1"} {
+		if err := os.Mkdir(filepath.Join(tmp, d), 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range []string{"full/f", "file"} {
+		if err := os.WriteFile(filepath.Join(tmp, f), nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		policy, dir, want string // want is empty for an error
+	}{
+		{KeepDirMerge, "new", "new"},
+		{KeepDirMerge, "empty", "empty"},
+		{KeepDirMerge, "full", "full"},
+		{KeepDirMerge, "file", ""},
+		{KeepDirSuffix, "new", "new"},
+		{KeepDirSuffix, "empty", "empty"},
+		{KeepDirSuffix, "full", "full-2"},
+		{KeepDirSuffix, "file", "file-1"},
+		{KeepDirError, "new", "new"},
+		{KeepDirError, "empty", "empty"},
+		{KeepDirError, "full", ""},
+		{KeepDirError, "file", ""},
+		{"rename", "full", ""},
+	}
+	for _, tt := range tests {
+		keepDirPolicy = tt.policy
+		got, err := keepDirPath(filepath.Join(tmp, tt.dir))
+		want := ""
+		if tt.want != "" {
+			want = filepath.Join(tmp, tt.want)
+		}
+		if got != want || (err != nil) != (tt.want == "") {
+			t.Errorf("-k-policy=%s %s: %q, %v, want %q", tt.policy, tt.dir, got, err, want)
+		}
+	}
+}
+
+// -max-depth counts the components left after sanitizing
+func TestPathDepth(t *testing.T) {
+	tests := []struct {
+		name string
+		want int
+	}{
+		{"", 0},
+		{"a", 1},
+		{"a/", 1},
+		{"a/b/c.txt", 3},
+		{"/a//b/", 2},
+		{"a/./b/../c", 3}, // ".." is dropped, not resolved,
+		{"../../a", 1},
+		{`a\b\c`, 3},
+	}
+	for _, tt := range tests {
+		if got := pathDepth(tt.name); got != tt.want {
+			t.Errorf("pathDepth(%q) = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStripControlChars(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"plain/name.txt", "plain/name.txt"},
+		{"a\tb\x7fc\u0085d", "abcd"},
+		{"invoice\u202efdp.exe", "invoicefdp.exe"},
+		{"zero\u200bwidth\ufeff", "zerowidth"},
+		{"\u2066\u2069/f", "_/f"},
+		{"dir/", "dir/"},
+	}
+	for _, tt := range tests {
+		if got := stripControlChars(tt.name); got != tt.want {
+			t.Errorf("stripControlChars(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
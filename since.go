@@ -0,0 +1,43 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+)
+
+var sinceArchive = "" // extract only entries new or changed since this older version of the archive
+
+type entryVersion struct {
+	crc  uint32
+	size uint64
+}
+
+// mark the entries that are the same in the older archive as skipped, comparing by raw name, CRC and size.
+// Returns the number of entries marked.
+func skipUnchanged(older string, files []*zip.File, skip []bool) (n int, err error) {
+	zr, err := zip.OpenReader(older)
+	if err != nil {
+		return
+	}
+	defer zr.Close()
+	prev := make(map[string]entryVersion, len(zr.File))
+	for _, f := range zr.File {
+		prev[f.Name] = entryVersion{f.CRC32, f.UncompressedSize64}
+	}
+	for i, f := range files {
+		if skip[i] {
+			continue
+		}
+		if v, ok := prev[f.Name]; ok && v.crc == f.CRC32 && v.size == f.UncompressedSize64 {
+			skip[i] = true
+			n++
+		}
+	}
+	return
+}
+
+func reportUnchanged(n int) {
+	if !quiet && n > 0 {
+		fmt.Printf("%d entries are unchanged since %s\n", n, sinceArchive)
+	}
+}
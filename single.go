@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mixcode/codepage-unzip/codepagezip"
+	"github.com/ulikunitz/xz"
+)
+
+// compressed single-file formats
+const (
+	FormatNone = iota
+	FormatGzip
+	FormatBzip2
+	FormatXz
+	FormatISO // an ISO9660 image; not a single file, but detected with the others
+)
+
+// detect a compressed single-file format, or an ISO9660 image, by the magic number
+func detectSingleFormat(filename string) (format int, err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	magic := make([]byte, 6)
+	n, err := io.ReadFull(f, magic)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return
+	}
+	magic = magic[:n]
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return FormatGzip, nil
+	case bytes.HasPrefix(magic, []byte("BZh")):
+		return FormatBzip2, nil
+	case bytes.HasPrefix(magic, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}):
+		return FormatXz, nil
+	}
+	if isISOImage(f) {
+		return FormatISO, nil
+	}
+	return FormatNone, nil
+}
+
+// the filename without its compression extension
+func stripCompressExt(filename string) string {
+	_, file := filepath.Split(filename)
+	ext := filepath.Ext(file)
+	switch strings.ToLower(ext) {
+	case ".gz", ".bz2", ".xz":
+		return file[:len(file)-len(ext)]
+	case ".tgz", ".txz":
+		return file[:len(file)-len(ext)] + ".tar"
+	}
+	return file + ".out"
+}
+
+// decompress a gzip, bzip2 or xz file.
+// The gzip original-name field (FNAME) is used as the output filename,
+// converted from the source codepage unless it is valid UTF-8 and -f is not given.
+func runSingle(filename string, format int) (err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	var r io.Reader
+	name := stripCompressExt(filename)
+	raw, from := name, UTF8 // the name as recorded, and its codepage
+	switch format {
+	case FormatGzip:
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		if zr.Name != "" {
+			// compress/gzip decodes FNAME as Latin-1; restore the raw bytes
+			b := make([]byte, 0, len(zr.Name))
+			for _, c := range zr.Name {
+				b = append(b, byte(c))
+			}
+			raw = string(b)
+			if wantsDetection() {
+				var names []string
+				if !utf8.Valid(b) {
+					names = append(names, raw)
+				}
+				defer useDetection(detectNames(names))()
+			}
+			// a valid UTF-8 name is taken as it is, unless -f says otherwise
+			from = convertFrom
+			if utf8.Valid(b) && (wantsDetection() || !codepageGiven()) {
+				from = UTF8
+			}
+			name, err = codepagezip.ConvertString(raw, from, convertTo)
+			if err != nil {
+				return fmt.Errorf("converting from %s to %s: %w", from, convertTo, err)
+			}
+		}
+		r = zr
+
+	case FormatBzip2:
+		r = bzip2.NewReader(f)
+
+	case FormatXz:
+		r, err = xz.NewReader(f)
+		if err != nil {
+			return
+		}
+	}
+
+	if cmd == CmdList {
+		fmt.Printf("%s\n", name)
+		return nil
+	}
+
+	if keepFileDir {
+		destDir, err = keepDirPath(filepath.Join(destDir, keepDirName(filename)))
+		if err != nil {
+			return
+		}
+	}
+	finish, err := openOutput(filename, nil)
+	if err != nil {
+		return
+	}
+	defer finish(&err)
+
+	outpath := filepath.Join(destDir, codepagezip.SanitizeComponent(name))
+	existing := outpath
+	if stagingFinal != "" {
+		existing = finalPath(outpath)
+	}
+	if st, e := os.Stat(existing); e == nil {
+		if st.IsDir() {
+			return fmt.Errorf("cannot create file %s", name)
+		}
+		if !overwrite {
+			fmt.Printf(tr("The output file '%s' already exists."), name)
+			if !promptYN(tr(" Overwrite? (y/N)"), false) {
+				return nil
+			}
+		}
+	}
+
+	if !quiet {
+		fmt.Printf("%s\n", name)
+	}
+	err = makeDir(destDir)
+	if err != nil {
+		return
+	}
+	fo, err := createFile(outpath)
+	if err != nil {
+		return
+	}
+	_, err = io.Copy(fo, r)
+	if e := fo.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		removeFile(outpath)
+		return
+	}
+	if nameMap != nil {
+		err = nameMap.add(raw, from, outpath)
+	}
+	return
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/mixcode/codepage-unzip/codepagezip"
+)
+
+const slugsMapFilename = "slugs.map"
+
+var asciiSlugs = false // rename output files to ASCII-only names
+
+// make an ASCII-only filename component
+func slugComponent(s string) string {
+	s = transliterate(s, "_")
+	var b strings.Builder
+	for _, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '.', c == '-':
+			b.WriteRune(c)
+		default:
+			// spaces and symbols; collapse repeats
+			if str := b.String(); str == "" || str[len(str)-1] != '_' {
+				b.WriteByte('_')
+			}
+		}
+	}
+	slug := strings.Trim(b.String(), "_")
+	if ext := path.Ext(slug); len(ext) == len(slug) {
+		// nothing left but the extension
+		slug = "file" + slug
+	}
+	return codepagezip.SanitizeComponent(slug)
+}
+
+// slugger makes unique ASCII-only paths for entry names
+type slugger struct {
+	dirs  map[string]string // original directory path -> slug path
+	used  map[string]bool   // slug paths in use, in lower case for case-insensitive filesystems
+	pairs [][2]string       // original and slug names, for the mapping file
+}
+
+func newSlugger() *slugger {
+	return &slugger{dirs: make(map[string]string), used: make(map[string]bool)}
+}
+
+// make a slug path unique by adding a number before the extension
+func (s *slugger) unique(p string) string {
+	ext := path.Ext(p)
+	base := p[:len(p)-len(ext)]
+	for i := 2; s.used[strings.ToLower(p)]; i++ {
+		p = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+	s.used[strings.ToLower(p)] = true
+	return p
+}
+
+// get the slug path of a directory
+func (s *slugger) dir(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	if d, ok := s.dirs[dir]; ok {
+		return d
+	}
+	parent, base := path.Split(dir)
+	d := s.unique(path.Join(s.dir(strings.TrimSuffix(parent, "/")), slugComponent(base)))
+	s.dirs[dir] = d
+	s.pairs = append(s.pairs, [2]string{dir + "/", d + "/"})
+	return d
+}
+
+// get the slug of an entry name
+func (s *slugger) name(name string) string {
+	p := codepagezip.SanitizePath(name)
+	if p == "" {
+		return name
+	}
+	if strings.HasSuffix(name, "/") || strings.HasSuffix(name, "\\") {
+		return s.dir(p) + "/"
+	}
+	parent, base := path.Split(p)
+	slug := s.unique(path.Join(s.dir(strings.TrimSuffix(parent, "/")), slugComponent(base)))
+	s.pairs = append(s.pairs, [2]string{p, slug})
+	return slug
+}
+
+// write the mapping between the slugs and the original names
+func (s *slugger) writeMap(dir string) (err error) {
+	f, err := createFile(filepath.Join(dir, slugsMapFilename))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	fmt.Fprintf(w, "# slug\toriginal name\n")
+	for _, p := range s.pairs {
+		fmt.Fprintf(w, "%s\t%s\n", p[1], p[0])
+	}
+	err = w.Flush()
+	if err != nil {
+		return
+	}
+	return f.Close()
+}
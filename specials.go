@@ -0,0 +1,68 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+)
+
+// how to handle FIFO, device and socket entries
+const (
+	SpecialsSkip   = "skip"   // report and skip
+	SpecialsError  = "error"  // fail the entry
+	SpecialsCreate = "create" // make the special file
+)
+
+var specialsPolicy = SpecialsSkip
+
+const specialModes = fs.ModeDevice | fs.ModeCharDevice | fs.ModeNamedPipe | fs.ModeSocket
+
+func checkSpecialsPolicy() error {
+	switch specialsPolicy {
+	case SpecialsSkip, SpecialsError, SpecialsCreate:
+		return nil
+	}
+	return fmt.Errorf("unknown -specials policy %q", specialsPolicy)
+}
+
+// describe the kind of a special file mode
+func specialKind(mode fs.FileMode) string {
+	switch {
+	case mode&fs.ModeNamedPipe != 0:
+		return "FIFO"
+	case mode&fs.ModeSocket != 0:
+		return "socket"
+	case mode&fs.ModeCharDevice != 0:
+		return "character device"
+	}
+	return "block device"
+}
+
+// handle an entry that is not a regular file, a directory or a symlink
+func writeSpecial(entry *zip.File, name, outpath string) (err error) {
+	kind := specialKind(entry.Mode())
+	switch specialsPolicy {
+	case SpecialsSkip:
+		warnf(WarnSkipped, "skipping %s %s", kind, name)
+		return nil
+	case SpecialsError:
+		return fmt.Errorf("the entry is a %s", kind)
+	}
+	err = checkDirBeneath(destDir, filepath.Dir(outpath))
+	if err != nil {
+		return
+	}
+	err = makeDir(filepath.Dir(outpath))
+	if err != nil {
+		return
+	}
+	if !quiet {
+		fmt.Printf("%s (%s)\n", name, kind)
+	}
+	err = makeSpecial(outpath, entry.Mode())
+	if err != nil {
+		return
+	}
+	return entryDone(entry, outpath)
+}
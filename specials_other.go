@@ -0,0 +1,12 @@
+//go:build !unix
+
+package main
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+func makeSpecial(path string, mode fs.FileMode) error {
+	return fmt.Errorf("cannot create a %s on this platform", specialKind(mode))
+}
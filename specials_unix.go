@@ -0,0 +1,17 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"syscall"
+)
+
+// make a special file. Zip archives do not record device numbers, so only FIFOs can be made.
+func makeSpecial(path string, mode fs.FileMode) error {
+	if mode&fs.ModeNamedPipe != 0 {
+		return syscall.Mkfifo(path, uint32(mode.Perm()))
+	}
+	return fmt.Errorf("cannot create a %s; the archive does not record what it refers to", specialKind(mode))
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+var stagingFinal = "" // the final output directory while extracting into a staging directory
+
+// make a temporary staging directory next to the final output directory, on the same filesystem
+func beginStaging(final string) (tmp string, err error) {
+	if st, err := os.Stat(final); err == nil && !st.IsDir() {
+		return "", fmt.Errorf("%s exists and is not a directory", final)
+	}
+	abs, err := filepath.Abs(final)
+	if err != nil {
+		return
+	}
+	parent := filepath.Dir(abs) // not the final directory itself, for -d .
+	err = os.MkdirAll(parent, fs.ModePerm)
+	if err != nil {
+		return
+	}
+	// MkdirTemp makes a private directory; files are extracted into a subdirectory of it made with the usual permissions
+	top, err := os.MkdirTemp(parent, ".codepage-unzip-*")
+	if err != nil {
+		return
+	}
+	tmp = filepath.Join(top, "out")
+	err = os.Mkdir(tmp, fs.ModePerm)
+	if err != nil {
+		os.Remove(top)
+		return "", err
+	}
+	return
+}
+
+// continue in the staging directory of an interrupted run, recorded in the checkpoint.
+// If it is gone, a new one is made; the checkpoint then finds none of its entries completed.
+func resumeStaging(tmp, final string) (string, error) {
+	abs, err := filepath.Abs(final)
+	if err != nil {
+		return "", err
+	}
+	if st, err := os.Stat(tmp); err == nil && st.IsDir() && filepath.Dir(filepath.Dir(tmp)) == filepath.Dir(abs) {
+		return tmp, nil
+	}
+	return beginStaging(final)
+}
+
+// the path in the final output directory of a path in the staging directory
+func finalPath(p string) string {
+	if stagingFinal == "" {
+		return p
+	}
+	rel, err := filepath.Rel(destDir, p)
+	if err != nil {
+		return p
+	}
+	return filepath.Join(stagingFinal, rel)
+}
+
+// move a completed staging directory into place.
+// A new output directory, or an empty one on Unix, is replaced by a single rename, which is atomic.
+// Into an existing directory the staged entries are renamed one by one: each appears, or replaces
+// a file, atomically, but not all of them at once. Conflicts between files and directories are
+// checked before anything is moved.
+func commitStaging(tmp, final string) (err error) {
+	if _, e := os.Lstat(final); os.IsNotExist(e) {
+		err = os.Rename(tmp, final)
+	} else if empty, e := isEmptyDir(final); e == nil && empty {
+		err = renameOverEmptyDir(tmp, final)
+	} else {
+		err = os.ErrExist
+	}
+	if err != nil {
+		err = checkMerge(tmp, final)
+		if err != nil {
+			return
+		}
+		err = mergeDir(tmp, final, final)
+		if err != nil {
+			return
+		}
+		return os.RemoveAll(filepath.Dir(tmp))
+	}
+	return os.Remove(filepath.Dir(tmp))
+}
+
+// check that the staged entries can be moved into dst: a directory only onto a directory or nothing,
+// and anything else not onto a directory
+func checkMerge(src, dst string) error {
+	list, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, d := range list {
+		to := filepath.Join(dst, d.Name())
+		st, err := os.Lstat(to)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir() && st.IsDir():
+			err = checkMerge(filepath.Join(src, d.Name()), to)
+			if err != nil {
+				return err
+			}
+		case d.IsDir() || st.IsDir():
+			return fmt.Errorf("cannot move %s into place: a file and a directory have the same name", to)
+		}
+	}
+	return nil
+}
+
+// rename the staged entries into dst, replacing files; final is the output directory, for backups
+func mergeDir(src, dst, final string) error {
+	list, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, d := range list {
+		from, to := filepath.Join(src, d.Name()), filepath.Join(dst, d.Name())
+		st, err := os.Lstat(to)
+		switch {
+		case os.IsNotExist(err):
+		case err != nil:
+			return err
+		case st.IsDir() && d.IsDir():
+			err = mergeDir(from, to, final)
+			if err != nil {
+				return err
+			}
+			continue
+		case st.Mode().IsRegular() && backupExisting.enabled:
+			err = backupFile(final, to)
+			if err != nil {
+				return err
+			}
+		}
+		err = os.Rename(from, to)
+		if err != nil {
+			return err
+		}
+		if syncDirs() {
+			dirtyDirs[dst] = true
+		}
+	}
+	return nil
+}
+
+// remove a staging directory
+func abortStaging(tmp string) error {
+	return os.RemoveAll(filepath.Dir(tmp))
+}
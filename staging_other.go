@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// rename a directory, which fails if newpath exists; the entries are then moved into it one by one
+func renameOverEmptyDir(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
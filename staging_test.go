@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCommitStaging(t *testing.T) {
+	write := func(p, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(p), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(p string) string {
+		b, _ := os.ReadFile(p)
+		return string(b)
+	}
+
+	for _, existing := range []string{"", "empty", "full"} {
+		final := filepath.Join(t.TempDir(), "final")
+		switch existing {
+		case "empty":
+			os.Mkdir(final, 0777)
+		case "full":
+			write(filepath.Join(final, "keep.txt"), "keep")
+			write(filepath.Join(final, "d", "old.txt"), "old")
+			write(filepath.Join(final, "a.txt"), "replaced")
+		}
+		tmp, err := beginStaging(final)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filepath.Dir(filepath.Dir(tmp)) != filepath.Dir(final) {
+			t.Errorf("%s: staging directory %s is not next to %s", existing, tmp, final)
+		}
+		write(filepath.Join(tmp, "a.txt"), "a")
+		write(filepath.Join(tmp, "d", "b.txt"), "b")
+		if err := commitStaging(tmp, final); err != nil {
+			t.Fatalf("%s: %v", existing, err)
+		}
+		if read(filepath.Join(final, "a.txt")) != "a" || read(filepath.Join(final, "d", "b.txt")) != "b" {
+			t.Errorf("%s: the staged files are not in place", existing)
+		}
+		if existing == "full" && (read(filepath.Join(final, "keep.txt")) != "keep" || read(filepath.Join(final, "d", "old.txt")) != "old") {
+			t.Errorf("%s: other files in the output directory were lost", existing)
+		}
+		if _, err := os.Stat(filepath.Dir(tmp)); !os.IsNotExist(err) {
+			t.Errorf("%s: the staging directory is left: %v", existing, err)
+		}
+	}
+}
+
+func TestCommitStagingConflict(t *testing.T) {
+	final := filepath.Join(t.TempDir(), "final")
+	if err := os.MkdirAll(filepath.Join(final, "x"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	tmp, err := beginStaging(final)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.txt", "x"} {
+		if err := os.WriteFile(filepath.Join(tmp, name), nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := commitStaging(tmp, final); err == nil {
+		t.Fatal("a file was moved onto a directory")
+	}
+	if _, err := os.Stat(filepath.Join(final, "a.txt")); !os.IsNotExist(err) {
+		t.Error("entries were moved before the conflict was found")
+	}
+}
@@ -0,0 +1,11 @@
+//go:build unix
+
+package main
+
+import "syscall"
+
+// rename a directory over an empty one, which rename(2) does atomically;
+// os.Rename refuses to replace a directory
+func renameOverEmptyDir(oldpath, newpath string) error {
+	return syscall.Rename(oldpath, newpath)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var showStats = false // print statistics after extraction
+
+// totals of a class of entries
+type statClass struct {
+	count      int
+	size       uint64
+	compressed uint64
+	elapsed    time.Duration
+}
+
+func (c *statClass) add(entry *zip.File, elapsed time.Duration) {
+	c.count++
+	c.size += entry.UncompressedSize64
+	c.compressed += entry.CompressedSize64
+	c.elapsed += elapsed
+}
+
+// extraction statistics by compression method and by how the name was decoded
+type extractStats struct {
+	byMethod map[string]*statClass
+	byName   map[string]*statClass
+}
+
+func newExtractStats() *extractStats {
+	return &extractStats{byMethod: make(map[string]*statClass), byName: make(map[string]*statClass)}
+}
+
+func methodName(method uint16) string {
+	switch method {
+	case zip.Store:
+		return "store"
+	case zip.Deflate:
+		return "deflate"
+	case MethodZstd:
+		return "zstd"
+	}
+	return fmt.Sprintf("method %d", method)
+}
+
+// how the name of an entry was decoded
+func nameClass(entry *zip.File) string {
+	switch {
+	case entry.Flags&FLAG_EFS != 0:
+		return "UTF-8 (EFS flag)"
+	case !entry.NonUTF8:
+		return "ASCII/UTF-8 (detected)"
+	}
+	return "converted from " + convertFrom
+}
+
+func (s *extractStats) add(entry *zip.File, elapsed time.Duration) {
+	for _, c := range []struct {
+		m   map[string]*statClass
+		key string
+	}{{s.byMethod, methodName(entry.Method)}, {s.byName, nameClass(entry)}} {
+		sc := c.m[c.key]
+		if sc == nil {
+			sc = &statClass{}
+			c.m[c.key] = sc
+		}
+		sc.add(entry, elapsed)
+	}
+}
+
+func printStatTable(title string, m map[string]*statClass) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fmt.Printf("%-24s %8s %14s %14s %10s\n", title, "entries", "bytes", "compressed", "time")
+	for _, k := range keys {
+		c := m[k]
+		fmt.Printf("%-24s %8d %14d %14d %10s\n", k, c.count, c.size, c.compressed, c.elapsed.Round(time.Millisecond))
+	}
+}
+
+func (s *extractStats) print() {
+	printStatTable("Method", s.byMethod)
+	fmt.Printf("\n")
+	printStatTable("Names", s.byName)
+}
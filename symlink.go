@@ -0,0 +1,283 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/mixcode/codepage-unzip/codepagezip"
+)
+
+// how to make symbolic link entries
+const (
+	SymlinkAuto     = "auto"     // a symlink if permitted, otherwise a junction for a directory on Windows, otherwise a copy
+	SymlinkLink     = "link"     // a symbolic link
+	SymlinkJunction = "junction" // an NTFS junction; directories on Windows only
+	SymlinkHardlink = "hardlink" // a hard link to the target file
+	SymlinkCopy     = "copy"     // a copy of the target
+	SymlinkSkip     = "skip"     // do not extract links
+	SymlinkFile     = "file"     // a regular file containing the target path
+)
+
+var symlinkPolicy = SymlinkAuto
+
+const maxLinkTarget = 4096
+
+// a link to be made after all other entries have been extracted
+type pendingLink struct {
+	entry   *zip.File
+	name    string
+	outpath string
+	target  string // in the native path form, relative to the directory of the link
+}
+
+var pendingLinks []pendingLink
+
+func checkSymlinkPolicy() error {
+	switch symlinkPolicy {
+	case SymlinkAuto, SymlinkLink, SymlinkJunction, SymlinkHardlink, SymlinkCopy, SymlinkSkip, SymlinkFile:
+		return nil
+	}
+	return fmt.Errorf("unknown symlink policy %q", symlinkPolicy)
+}
+
+// read the target of a symlink entry and queue the link.
+// Links are made last, so that no entry is written through them and their targets exist.
+func deferSymlink(entry *zip.File, name, outpath string) (err error) {
+	if symlinkPolicy == SymlinkSkip {
+		if !quiet {
+			fmt.Printf("skipping symlink %s\n", name)
+		}
+		return nil
+	}
+	r, err := entry.Open()
+	if err != nil {
+		return
+	}
+	defer r.Close()
+	b, err := io.ReadAll(io.LimitReader(r, maxLinkTarget+1))
+	if err != nil {
+		return
+	}
+	if len(b) > maxLinkTarget || len(b) == 0 {
+		return fmt.Errorf("symlink %s has an invalid target", name)
+	}
+	target := string(b)
+	if entry.NonUTF8 {
+		target, err = codepagezip.ConvertString(target, nameEncoding(entry), convertTo)
+		if err != nil {
+			return
+		}
+	}
+	target = filepath.FromSlash(target)
+	if filepath.IsAbs(target) || filepath.VolumeName(target) != "" ||
+		!isBeneath(destDir, filepath.Join(filepath.Dir(outpath), target)) {
+		return fmt.Errorf("symlink %s points outside of the output directory: %s", name, target)
+	}
+	pendingLinks = append(pendingLinks, pendingLink{entry, name, outpath, target})
+	return nil
+}
+
+var symlinkPermitted *bool
+
+// check whether this process may make symbolic links, e.g. without SeCreateSymbolicLinkPrivilege on Windows
+func canSymlink() bool {
+	if symlinkPermitted == nil {
+		ok := false
+		tmp, err := os.MkdirTemp("", "codepage-unzip-*")
+		if err == nil {
+			ok = os.Symlink("target", filepath.Join(tmp, "link")) == nil
+			os.RemoveAll(tmp)
+		}
+		symlinkPermitted = &ok
+	}
+	return *symlinkPermitted
+}
+
+// make a queued link according to symlinkPolicy
+func makeLink(l pendingLink) (err error) {
+	err = checkDirBeneath(destDir, filepath.Dir(l.outpath))
+	if err != nil {
+		return
+	}
+	err = makeDir(filepath.Dir(l.outpath))
+	if err != nil {
+		return
+	}
+	resolved, err := resolveLinkTarget(l)
+	if err != nil {
+		return
+	}
+	existing := l.outpath
+	if stagingFinal != "" {
+		existing = finalPath(l.outpath)
+	}
+	if _, e := os.Lstat(existing); e == nil {
+		if !overwrite {
+			fmt.Printf(tr("The output file '%s' already exists."), l.name)
+			if !promptYN(tr(" Overwrite? (y/N)"), false) {
+				return nil
+			}
+		}
+		if e := removeFile(l.outpath); e != nil && !os.IsNotExist(e) {
+			return e
+		}
+	}
+
+	policy := symlinkPolicy
+	if policy == SymlinkAuto {
+		st, e := os.Stat(resolved)
+		switch {
+		case canSymlink():
+			policy = SymlinkLink
+		case runtime.GOOS == "windows" && e == nil && st.IsDir():
+			policy = SymlinkJunction
+		default:
+			policy = SymlinkCopy
+		}
+	}
+	if !quiet {
+		fmt.Printf("%s -> %s (%s)\n", l.name, filepath.ToSlash(l.target), policy)
+	}
+	switch policy {
+	case SymlinkLink:
+		err = makeSymlink(l.target, l.outpath)
+	case SymlinkJunction:
+		var abs string
+		abs, err = filepath.Abs(resolved)
+		if err == nil {
+			err = makeJunction(l.outpath, abs)
+		}
+	case SymlinkHardlink:
+		if st, e := os.Stat(resolved); e == nil && st.IsDir() {
+			return fmt.Errorf("cannot make a hard link to directory %s", filepath.ToSlash(l.target))
+		}
+		err = makeHardLink(resolved, l.outpath)
+	case SymlinkCopy:
+		err = copyTree(l.outpath, resolved)
+	}
+	if err != nil {
+		return
+	}
+	return entryDone(l.entry, l.outpath)
+}
+
+// resolve the target of a queued link on disk.
+// Links made earlier may change what the path text means, e.g. l1 -> . and then l1/l2 -> ../x,
+// so the parent directory of the link must not go through a link, and the target must be
+// beneath the output directory after following the links on its way.
+func resolveLinkTarget(l pendingLink) (string, error) {
+	absDest, err := filepath.Abs(destDir)
+	if err != nil {
+		return "", err
+	}
+	root, err := filepath.EvalSymlinks(absDest)
+	if err != nil {
+		return "", err
+	}
+	parent, err := filepath.Abs(filepath.Dir(l.outpath))
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(absDest, parent)
+	if err != nil {
+		return "", err
+	}
+	realParent, err := filepath.EvalSymlinks(parent)
+	if err != nil {
+		return "", err
+	}
+	if realParent != filepath.Join(root, rel) {
+		return "", fmt.Errorf("the path of symlink %s goes through another link", l.name)
+	}
+
+	// leading .. components, and then names; .. after a name may mean anything once the name is a link
+	p := realParent
+	parts := strings.Split(l.target, string(filepath.Separator))
+	i := 0
+	for ; i < len(parts) && parts[i] == ".."; i++ {
+		p = filepath.Dir(p)
+	}
+	for _, c := range parts[i:] {
+		if c == ".." {
+			return "", fmt.Errorf("symlink %s has .. after a name in its target: %s", l.name, filepath.ToSlash(l.target))
+		}
+	}
+	p, err = realPath(filepath.Join(p, filepath.Join(parts[i:]...)))
+	if err != nil {
+		return "", err
+	}
+	if !isBeneath(root, p) {
+		return "", fmt.Errorf("symlink %s points outside of the output directory: %s", l.name, filepath.ToSlash(l.target))
+	}
+	return p, nil
+}
+
+// get the real path of p, following the links in the part of it that exists
+func realPath(p string) (string, error) {
+	rest := ""
+	for {
+		r, err := filepath.EvalSymlinks(p)
+		if err == nil {
+			return filepath.Join(r, rest), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			return filepath.Join(p, rest), nil
+		}
+		rest = filepath.Join(filepath.Base(p), rest)
+		p = parent
+	}
+}
+
+// copy a file, or a directory recursively.
+// A link like d/self -> . would copy a directory into itself while walking it, which is refused.
+func copyTree(dst, src string) error {
+	st, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !st.IsDir() {
+		return copyFile(dst, src)
+	}
+	absDst, err := filepath.Abs(dst)
+	if err != nil {
+		return err
+	}
+	realDst, err := realPath(absDst)
+	if err != nil {
+		return err
+	}
+	realSrc, err := filepath.EvalSymlinks(src)
+	if err != nil {
+		return err
+	}
+	if isBeneath(realSrc, realDst) {
+		return fmt.Errorf("cannot copy directory %s into itself", src)
+	}
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if maxDepth > 0 && rel != "." && strings.Count(rel, string(filepath.Separator))+1 > maxDepth {
+			return fmt.Errorf("copying %s goes deeper than %d levels (see -max-depth)", src, maxDepth)
+		}
+		to := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return makeDir(to)
+		}
+		return copyFile(to, path)
+	})
+}
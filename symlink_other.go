@@ -0,0 +1,9 @@
+//go:build !windows
+
+package main
+
+import "fmt"
+
+func makeJunction(link, target string) error {
+	return fmt.Errorf("junctions are only supported on Windows")
+}
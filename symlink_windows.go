@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"unicode/utf16"
+
+	"golang.org/x/sys/windows"
+)
+
+const fsctlSetReparsePoint = 0x000900A4 // FSCTL_SET_REPARSE_POINT
+
+// make an NTFS junction, which needs no privilege unlike a symbolic link.
+// The reparse point is set directly on a new empty directory; names never go through a shell.
+func makeJunction(link, target string) (err error) {
+	target, err = filepath.Abs(target)
+	if err != nil {
+		return
+	}
+	err = os.Mkdir(link, 0777)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(link)
+		}
+	}()
+
+	p, err := windows.UTF16PtrFromString(link)
+	if err != nil {
+		return
+	}
+	h, err := windows.CreateFile(p, windows.GENERIC_WRITE, 0, nil, windows.OPEN_EXISTING,
+		windows.FILE_FLAG_OPEN_REPARSE_POINT|windows.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return
+	}
+	defer windows.CloseHandle(h)
+
+	// REPARSE_DATA_BUFFER for a mount point: the substitute name, then the print name, each NUL-terminated
+	sub := utf16.Encode([]rune(`\??\` + target))
+	printName := utf16.Encode([]rune(target))
+	pathLen := (len(sub) + 1 + len(printName) + 1) * 2
+	buf := make([]byte, 8+8+pathLen)
+	binary.LittleEndian.PutUint32(buf[0:], windows.IO_REPARSE_TAG_MOUNT_POINT)
+	binary.LittleEndian.PutUint16(buf[4:], uint16(8+pathLen)) // ReparseDataLength
+	binary.LittleEndian.PutUint16(buf[8:], 0)                 // SubstituteNameOffset
+	binary.LittleEndian.PutUint16(buf[10:], uint16(len(sub)*2))
+	binary.LittleEndian.PutUint16(buf[12:], uint16((len(sub)+1)*2)) // PrintNameOffset
+	binary.LittleEndian.PutUint16(buf[14:], uint16(len(printName)*2))
+	o := 16
+	for _, c := range sub {
+		binary.LittleEndian.PutUint16(buf[o:], c)
+		o += 2
+	}
+	o += 2
+	for _, c := range printName {
+		binary.LittleEndian.PutUint16(buf[o:], c)
+		o += 2
+	}
+
+	var n uint32
+	err = windows.DeviceIoControl(h, fsctlSetReparsePoint, &buf[0], uint32(len(buf)), nil, 0, &n, nil)
+	if err != nil {
+		return fmt.Errorf("making junction %s: %v", link, err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf8"
+)
+
+const textScanLimit = 1 << 20 // bytes to read from each file
+
+var (
+	scanText       = false // scan extracted text files for broken encodings
+	extractedFiles []string
+)
+
+// check the head of a file; returns a description of the problem, or "" if it is fine or not text
+func mojibakeProblem(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	b, err := io.ReadAll(io.LimitReader(f, textScanLimit))
+	if err != nil || len(b) == 0 || bytes.IndexByte(b, 0) >= 0 {
+		return ""
+	}
+	if len(b) == textScanLimit {
+		// do not count a character cut at the end
+		for i := 0; i < utf8.UTFMax-1 && len(b) > 0 && !utf8.Valid(b); i++ {
+			b = b[:len(b)-1]
+		}
+	}
+	if !utf8.Valid(b) {
+		return "not valid UTF-8"
+	}
+	if n := bytes.Count(b, []byte("�")); n > 0 {
+		return fmt.Sprintf("%d replacement characters", n)
+	}
+	return ""
+}
+
+// report extracted text files whose contents are likely in a legacy codepage or already garbled
+func reportMojibake(paths []string) {
+	found := 0
+	for _, path := range paths {
+		if p := mojibakeProblem(path); p != "" {
+			if found == 0 {
+				fmt.Printf("Text files that may need content transcoding (see -convert-content):\n")
+			}
+			fmt.Printf("  %s: %s\n", path, p)
+			found++
+		}
+	}
+	if found == 0 && !quiet {
+		fmt.Printf("No text files with broken UTF-8 found\n")
+	}
+}
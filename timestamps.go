@@ -0,0 +1,36 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"time"
+
+	"github.com/mixcode/codepage-unzip/codepagezip"
+)
+
+var sourceTZ = "" // the time zone DOS timestamps were recorded in; empty for the local zone
+
+// the location of -source-tz
+var sourceLoc = time.Local
+
+func parseSourceTZ() error {
+	if sourceTZ == "" {
+		sourceLoc = time.Local
+		return nil
+	}
+	loc, err := time.LoadLocation(sourceTZ)
+	if err != nil {
+		return fmt.Errorf("-source-tz: %w", err)
+	}
+	sourceLoc = loc
+	return nil
+}
+
+// set the modification time of an extracted file to that of its entry; an entry without one leaves it alone
+func restoreModTime(entry *zip.File, outpath string) error {
+	if !codepagezip.HasModTime(entry) {
+		return nil
+	}
+	t := codepagezip.ModTime(entry, sourceLoc)
+	return setFileTimes(outpath, t, t)
+}
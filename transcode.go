@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/mixcode/codepage-unzip/codepagezip"
+)
+
+var (
+	convertContent     = ""                    // -convert-content
+	convertContentExts = make(map[string]bool) // lowercase extensions without a dot; "*" for all files
+)
+
+// parse the extensions of -convert-content, like "txt,csv"
+func parseConvertContent(spec string) {
+	for _, ext := range strings.Split(spec, ",") {
+		ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+		if ext != "" {
+			convertContentExts[ext] = true
+		}
+	}
+}
+
+// report whether the content of an entry is to be converted
+func wantsContentConversion(name string) bool {
+	if len(convertContentExts) == 0 {
+		return false
+	}
+	return convertContentExts["*"] || convertContentExts[strings.ToLower(strings.TrimPrefix(path.Ext(name), "."))]
+}
+
+// copy the content of an entry, converting it from -f to -t as a stream.
+// Returns the number of bytes read from the entry, to be checked against its size.
+func copyConverted(w io.Writer, r io.Reader, size uint64) (int64, error) {
+	in := &progressReader{r: io.LimitReader(r, int64(size)+1)}
+	cr, err := codepagezip.NewConvertingReader(in, convertFrom, convertTo)
+	if err != nil {
+		return 0, fmt.Errorf("converting from %s to %s: %w", convertFrom, convertTo, err)
+	}
+	_, err = io.Copy(w, cr)
+	n := in.n.Load()
+	if err != nil {
+		return n, fmt.Errorf("converting the content from %s to %s: %w", convertFrom, convertTo, err)
+	}
+	if n > int64(size) {
+		return n, fmt.Errorf("the entry data is longer than its declared size; the stream may be corrupted")
+	}
+	return n, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// An external name transform runs as a subprocess and talks JSON over stdin/stdout, one object per line.
+// For each entry the tool sends a transformRequest and reads back a transformResponse.
+
+type transformRequest struct {
+	Raw      string `json:"raw"`      // raw name bytes in hex
+	Encoding string `json:"encoding"` // the codepage the name was converted from
+	Name     string `json:"name"`     // the converted name
+	Size     uint64 `json:"size"`     // uncompressed size
+	Dir      bool   `json:"dir"`      // the entry is a directory
+}
+
+type transformResponse struct {
+	Name  string `json:"name,omitempty"`  // the new name; empty to keep the name
+	Skip  bool   `json:"skip,omitempty"`  // do not extract the entry
+	Error string `json:"error,omitempty"` // stop with an error
+}
+
+type transformProc struct {
+	cmd *exec.Cmd
+	in  io.WriteCloser
+	enc *json.Encoder
+	dec *json.Decoder
+}
+
+// start a transform command. The command line is split by spaces; no shell quoting is done.
+func startTransform(command string) (t *transformProc, err error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty transform command")
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stderr = os.Stderr
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return
+	}
+	err = cmd.Start()
+	if err != nil {
+		return
+	}
+	return &transformProc{cmd: cmd, in: in, enc: json.NewEncoder(in), dec: json.NewDecoder(out)}, nil
+}
+
+// ask the transform for the name of an entry
+func (t *transformProc) apply(entry *zip.File, name string) (newName string, skip bool, err error) {
+	req := transformRequest{
+		Raw:      hex.EncodeToString([]byte(entry.Name)),
+		Encoding: nameEncoding(entry),
+		Name:     name,
+		Size:     entry.UncompressedSize64,
+		Dir:      strings.HasSuffix(name, "/"),
+	}
+	err = t.enc.Encode(&req)
+	if err != nil {
+		return "", false, fmt.Errorf("transform: %w", err)
+	}
+	var res transformResponse
+	err = t.dec.Decode(&res)
+	if err != nil {
+		return "", false, fmt.Errorf("transform: reading the response for %s: %w", name, err)
+	}
+	if res.Error != "" {
+		return "", false, fmt.Errorf("transform: %s: %s", name, res.Error)
+	}
+	if res.Name == "" {
+		return name, res.Skip, nil
+	}
+	return res.Name, res.Skip, nil
+}
+
+func (t *transformProc) Close() error {
+	t.in.Close()
+	return t.cmd.Wait()
+}
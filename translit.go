@@ -0,0 +1,316 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Transliteration of converted names to ASCII
+
+// Latin letters with diacritics and ligatures
+var latinTable = map[rune]string{
+	'À': "A", 'Á': "A", 'Â': "A", 'Ã': "A", 'Ä': "A", 'Å': "A", 'Æ': "AE", 'Ç': "C",
+	'È': "E", 'É': "E", 'Ê': "E", 'Ë': "E", 'Ì': "I", 'Í': "I", 'Î': "I", 'Ï': "I",
+	'Ð': "D", 'Ñ': "N", 'Ò': "O", 'Ó': "O", 'Ô': "O", 'Õ': "O", 'Ö': "O", 'Ø': "O",
+	'Ù': "U", 'Ú': "U", 'Û': "U", 'Ü': "U", 'Ý': "Y", 'Þ': "TH", 'ß': "ss",
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'æ': "ae", 'ç': "c",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i",
+	'ð': "d", 'ñ': "n", 'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ý': "y", 'þ': "th", 'ÿ': "y",
+	'Ā': "A", 'ā': "a", 'Ă': "A", 'ă': "a", 'Ą': "A", 'ą': "a", 'Ć': "C", 'ć': "c",
+	'Č': "C", 'č': "c", 'Ď': "D", 'ď': "d", 'Đ': "D", 'đ': "d", 'Ē': "E", 'ē': "e",
+	'Ė': "E", 'ė': "e", 'Ę': "E", 'ę': "e", 'Ě': "E", 'ě': "e", 'Ğ': "G", 'ğ': "g",
+	'Ī': "I", 'ī': "i", 'Į': "I", 'į': "i", 'İ': "I", 'ı': "i", 'Ł': "L", 'ł': "l",
+	'Ń': "N", 'ń': "n", 'Ň': "N", 'ň': "n", 'Ō': "O", 'ō': "o", 'Ő': "O", 'ő': "o",
+	'Œ': "OE", 'œ': "oe", 'Ř': "R", 'ř': "r", 'Ś': "S", 'ś': "s", 'Ş': "S", 'ş': "s",
+	'Š': "S", 'š': "s", 'Ţ': "T", 'ţ': "t", 'Ť': "T", 'ť': "t", 'Ū': "U", 'ū': "u",
+	'Ů': "U", 'ů': "u", 'Ű': "U", 'ű': "u", 'Ų': "U", 'ų': "u", 'Ÿ': "Y", 'Ź': "Z",
+	'ź': "z", 'Ż': "Z", 'ż': "z", 'Ž': "Z", 'ž': "z",
+}
+
+// Hepburn romaji of kana, by hiragana; katakana is mapped to hiragana first
+var kanaTable = map[string]string{
+	"あ": "a", "い": "i", "う": "u", "え": "e", "お": "o",
+	"か": "ka", "き": "ki", "く": "ku", "け": "ke", "こ": "ko",
+	"が": "ga", "ぎ": "gi", "ぐ": "gu", "げ": "ge", "ご": "go",
+	"さ": "sa", "し": "shi", "す": "su", "せ": "se", "そ": "so",
+	"ざ": "za", "じ": "ji", "ず": "zu", "ぜ": "ze", "ぞ": "zo",
+	"た": "ta", "ち": "chi", "つ": "tsu", "て": "te", "と": "to",
+	"だ": "da", "ぢ": "ji", "づ": "zu", "で": "de", "ど": "do",
+	"な": "na", "に": "ni", "ぬ": "nu", "ね": "ne", "の": "no",
+	"は": "ha", "ひ": "hi", "ふ": "fu", "へ": "he", "ほ": "ho",
+	"ば": "ba", "び": "bi", "ぶ": "bu", "べ": "be", "ぼ": "bo",
+	"ぱ": "pa", "ぴ": "pi", "ぷ": "pu", "ぺ": "pe", "ぽ": "po",
+	"ま": "ma", "み": "mi", "む": "mu", "め": "me", "も": "mo",
+	"や": "ya", "ゆ": "yu", "よ": "yo",
+	"ら": "ra", "り": "ri", "る": "ru", "れ": "re", "ろ": "ro",
+	"わ": "wa", "ゐ": "i", "ゑ": "e", "を": "o", "ん": "n", "ゔ": "vu",
+	"ぁ": "a", "ぃ": "i", "ぅ": "u", "ぇ": "e", "ぉ": "o", "ゃ": "ya", "ゅ": "yu", "ょ": "yo", "ゎ": "wa",
+
+	"きゃ": "kya", "きゅ": "kyu", "きょ": "kyo", "ぎゃ": "gya", "ぎゅ": "gyu", "ぎょ": "gyo",
+	"しゃ": "sha", "しゅ": "shu", "しょ": "sho", "じゃ": "ja", "じゅ": "ju", "じょ": "jo",
+	"ちゃ": "cha", "ちゅ": "chu", "ちょ": "cho", "ぢゃ": "ja", "ぢゅ": "ju", "ぢょ": "jo",
+	"にゃ": "nya", "にゅ": "nyu", "にょ": "nyo", "ひゃ": "hya", "ひゅ": "hyu", "ひょ": "hyo",
+	"びゃ": "bya", "びゅ": "byu", "びょ": "byo", "ぴゃ": "pya", "ぴゅ": "pyu", "ぴょ": "pyo",
+	"みゃ": "mya", "みゅ": "myu", "みょ": "myo", "りゃ": "rya", "りゅ": "ryu", "りょ": "ryo",
+	"しぇ": "she", "じぇ": "je", "ちぇ": "che", "てぃ": "ti", "でぃ": "di", "とぅ": "tu", "どぅ": "du",
+	"ふぁ": "fa", "ふぃ": "fi", "ふぇ": "fe", "ふぉ": "fo", "うぃ": "wi", "うぇ": "we", "うぉ": "wo",
+	"ゔぁ": "va", "ゔぃ": "vi", "ゔぇ": "ve", "ゔぉ": "vo", "つぁ": "tsa",
+}
+
+// convert a katakana rune to hiragana
+func toHiragana(r rune) rune {
+	if r >= 'ァ' && r <= 'ヶ' {
+		return r - ('ァ' - 'ぁ')
+	}
+	return r
+}
+
+// romanize kana at the beginning of s; returns the romaji and the number of bytes consumed
+func romanizeKana(s string) (string, int) {
+	r1, n1 := utf8.DecodeRuneInString(s)
+	h1 := string(toHiragana(r1))
+	if n1 < len(s) {
+		r2, n2 := utf8.DecodeRuneInString(s[n1:])
+		if ro, ok := kanaTable[h1+string(toHiragana(r2))]; ok {
+			return ro, n1 + n2
+		}
+	}
+	if ro, ok := kanaTable[h1]; ok {
+		return ro, n1
+	}
+	return "", 0
+}
+
+// romanize kana at the beginning of s by Hepburn, writing to out.
+// Returns the number of bytes consumed, or 0 if s does not begin with kana.
+func hepburn(s string, out *strings.Builder) int {
+	r, n := utf8.DecodeRuneInString(s)
+	switch {
+	case r == 'っ' || r == 'ッ': // small tsu; double the next consonant
+		ro, m := romanizeKana(s[n:])
+		if m == 0 {
+			return n
+		}
+		if ro[0] == 'c' {
+			out.WriteByte('t') // tch
+		} else if !strings.ContainsRune("aiueon", rune(ro[0])) {
+			out.WriteByte(ro[0])
+		}
+		out.WriteString(ro)
+		return n + m
+
+	case r == 'ー': // long vowel mark; repeat the last vowel
+		if str := out.String(); str != "" && strings.ContainsRune("aiueo", rune(str[len(str)-1])) {
+			out.WriteByte(str[len(str)-1])
+		}
+		return n
+
+	case (r >= 'ぁ' && r <= 'ゖ') || (r >= 'ァ' && r <= 'ヶ'):
+		ro, m := romanizeKana(s)
+		if m == 0 {
+			return 0
+		}
+		out.WriteString(ro)
+		return m
+	}
+	return 0
+}
+
+// Revised Romanization of Korean, by the jamo of a Hangul syllable
+var (
+	rrInitial = []string{"g", "kk", "n", "d", "tt", "r", "m", "b", "pp", "s", "ss", "", "j", "jj", "ch", "k", "t", "p", "h"}
+	rrMedial  = []string{"a", "ae", "ya", "yae", "eo", "e", "yeo", "ye", "o", "wa", "wae", "oe", "yo", "u", "wo", "we", "wi", "yu", "eu", "ui", "i"}
+	rrFinal   = []string{"", "k", "k", "k", "n", "n", "n", "t", "l", "k", "m", "l", "l", "l", "p", "l", "m", "p", "p", "t", "t", "ng", "t", "t", "k", "t", "p", "t"}
+)
+
+// romanize a Hangul syllable at the beginning of s by the Revised Romanization of Korean, writing to out.
+// Sound changes between syllables are not applied.
+func revisedRomanization(s string, out *strings.Builder) int {
+	r, n := utf8.DecodeRuneInString(s)
+	if r < 0xac00 || r > 0xd7a3 {
+		return 0
+	}
+	i := int(r - 0xac00)
+	out.WriteString(rrInitial[i/588])
+	out.WriteString(rrMedial[i%588/28])
+	out.WriteString(rrFinal[i%28])
+	return n
+}
+
+// Cyrillic by ISO 9 system B, the ASCII-only variant (GOST 7.79 B), in lower case
+var iso9Table = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "j", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "x", 'ц': "cz",
+	'ч': "ch", 'ш': "sh", 'щ': "shh", 'ъ': "``", 'ы': "y`", 'ь': "`", 'э': "e`", 'ю': "yu",
+	'я': "ya", 'є': "ye", 'і': "i", 'ї': "yi", 'ґ': "g`", 'ў': "u`", 'ђ': "dj", 'ј': "j",
+	'љ': "lj", 'њ': "nj", 'ћ': "c`", 'џ': "dh", 'ѓ': "g`", 'ќ': "k`", 'ѕ': "z`",
+}
+
+// transliterate a Cyrillic letter at the beginning of s by ISO 9 system B, writing to out
+func iso9(s string, out *strings.Builder) int {
+	r, n := utf8.DecodeRuneInString(s)
+	lower := unicode.ToLower(r)
+	t, ok := iso9Table[lower]
+	if !ok || !unicode.Is(unicode.Cyrillic, r) {
+		return 0
+	}
+	if lower == 'ц' {
+		// "c" before i, e, y and j
+		next, _ := utf8.DecodeRuneInString(s[n:])
+		if strings.ContainsRune("иеыйiєeіїј", unicode.ToLower(next)) {
+			t = "c"
+		}
+	}
+	if lower != r {
+		// capitalize; keep the rest in upper case if the next letter is also in upper case
+		next, _ := utf8.DecodeRuneInString(s[n:])
+		if unicode.IsUpper(next) {
+			t = strings.ToUpper(t)
+		} else {
+			t = strings.ToUpper(t[:1]) + t[1:]
+		}
+	}
+	out.WriteString(t)
+	return n
+}
+
+// fold Latin letters with diacritics, writing to out
+func latinFold(s string, out *strings.Builder) int {
+	r, n := utf8.DecodeRuneInString(s)
+	if t, ok := latinTable[r]; ok {
+		out.WriteString(t)
+		return n
+	}
+	return 0
+}
+
+// Hanyu Pinyin by character, made from pinyinReadings at the first use
+var (
+	pinyinOnce  sync.Once
+	pinyinTable map[rune]string
+)
+
+// Hanyu Pinyin without tones, writing to out.
+// Each character gets its most common reading, as the word it is in is not known.
+func pinyin(s string, out *strings.Builder) int {
+	pinyinOnce.Do(func() {
+		pinyinTable = make(map[rune]string)
+		for _, line := range pinyinReadings {
+			syllable, chars, _ := strings.Cut(line, " ")
+			for _, r := range chars {
+				pinyinTable[r] = syllable
+			}
+		}
+	})
+	r, n := utf8.DecodeRuneInString(s)
+	if t, ok := pinyinTable[r]; ok {
+		out.WriteString(t)
+		return n
+	}
+	return 0
+}
+
+// a transliteration scheme converts a character, or a few, at the beginning of a string and writes to out.
+// It returns the number of bytes consumed, or 0 if it does not handle the character.
+//
+// The schemes work character by character from tables. Pinyin is not selected by default, since kanji
+// and hanja are read differently in Japanese and Korean, and Japanese readings need a dictionary.
+type translitScheme func(s string, out *strings.Builder) int
+
+var translitSchemes = map[string]translitScheme{
+	"hepburn": hepburn,             // Japanese kana
+	"rr":      revisedRomanization, // Korean Hangul
+	"iso9":    iso9,                // Cyrillic
+	"latin":   latinFold,           // Latin letters with diacritics
+	"pinyin":  pinyin,              // Chinese characters, read in Mandarin
+}
+
+var (
+	translitNames = "hepburn,rr,iso9,latin" // -translit flag
+	activeSchemes []translitScheme
+)
+
+// select the transliteration schemes from a comma-separated list
+func selectSchemes(names string) error {
+	activeSchemes = nil
+	for _, name := range strings.Split(names, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		sc, ok := translitSchemes[name]
+		if !ok && name == "kanji" {
+			return fmt.Errorf("transliteration scheme '%s' is not supported; Japanese readings of kanji need a dictionary (pinyin reads them in Mandarin)", name)
+		}
+		if !ok {
+			return fmt.Errorf("unknown transliteration scheme '%s'", name)
+		}
+		activeSchemes = append(activeSchemes, sc)
+	}
+	return nil
+}
+
+// transliterate a string to ASCII with the selected schemes.
+// Characters without a transliteration are replaced by unknown.
+func transliterate(s string, unknown string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if s[i] < utf8.RuneSelf {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+		n := 0
+		for _, sc := range activeSchemes {
+			n = sc(s[i:], &b)
+			if n > 0 {
+				break
+			}
+		}
+		if n == 0 {
+			_, n = utf8.DecodeRuneInString(s[i:])
+			b.WriteString(unknown)
+		}
+		i += n
+	}
+	return b.String()
+}
+
+// print the transliteration of the entry names of an archive, or of lines from stdin if no archive is given
+func runTranslit(args []string) (err error) {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: translit [ZIPfile]")
+	}
+	if len(args) == 0 {
+		sc := bufio.NewScanner(os.Stdin)
+		for sc.Scan() {
+			fmt.Printf("%s\n", transliterate(sc.Text(), "?"))
+		}
+		return sc.Err()
+	}
+
+	zr, err := zip.OpenReader(args[0])
+	if err != nil {
+		return
+	}
+	defer zr.Close()
+	if wantsDetection() {
+		defer applyDetection(zr.File)()
+	}
+	for _, f := range zr.File {
+		name, err := convertName(f)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\t%s\n", transliterate(name, "?"), name)
+	}
+	return
+}
@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestTransliterate(t *testing.T) {
+	defer selectSchemes(translitNames)
+	tests := []struct {
+		schemes, s, want string
+	}{
+		{"hepburn", "しんぶん.txt", "shinbun.txt"},
+		{"rr", "한글", "hangeul"},
+		{"iso9", "Москва", "Moskva"},
+		{"latin", "Café", "Cafe"},
+		{"pinyin", "中文/北京.txt", "zhongwen/beijing.txt"},
+		{"pinyin", "绿", "lu"},
+		{"hepburn,rr,iso9,latin", "中文", "??"}, // not by default
+		{"hepburn,pinyin", "東京の地図", "dongjingnodetu"},
+	}
+	for _, tt := range tests {
+		if err := selectSchemes(tt.schemes); err != nil {
+			t.Fatal(err)
+		}
+		if got := transliterate(tt.s, "?"); got != tt.want {
+			t.Errorf("transliterate(%q) with %s = %q, want %q", tt.s, tt.schemes, got, tt.want)
+		}
+	}
+	if err := selectSchemes("kanji"); err == nil {
+		t.Errorf("the kanji scheme is accepted")
+	}
+}
@@ -0,0 +1,4 @@
+package main
+
+// Windows has no time zone database for -source-tz; embed one
+import _ "time/tzdata"
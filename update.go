@@ -0,0 +1,29 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+
+	"github.com/mixcode/codepage-unzip/codepagezip"
+)
+
+var updateOnly = false // extract only files that are missing or older than their entries
+
+// files left as they are by -update in the current archive
+var upToDate = 0
+
+// check if an existing file is to be replaced by -update
+func needsUpdate(entry *zip.File, st os.FileInfo) bool {
+	if codepagezip.IsNewer(entry, sourceLoc, st.ModTime()) {
+		return true
+	}
+	upToDate++
+	return false
+}
+
+func reportUpToDate() {
+	if !quiet && upToDate > 0 {
+		fmt.Printf("%d files are up to date\n", upToDate)
+	}
+}
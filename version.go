@@ -0,0 +1,52 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/mixcode/codepage-unzip/codepagezip"
+)
+
+// print the version of the program, and how it was built.
+func printVersion(w io.Writer, prog string) {
+	version, commit, tags := "(devel)", "unknown", ""
+	modified := false
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if bi.Main.Version != "" {
+			version = bi.Main.Version
+		}
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				commit = s.Value
+			case "vcs.modified":
+				modified = s.Value == "true"
+			case "-tags":
+				tags = s.Value
+			}
+		}
+	}
+	if modified {
+		commit += " (modified)"
+	}
+	if tags == "" {
+		tags = "none"
+	}
+
+	var methods []string
+	for _, m := range []uint16{zip.Store, zip.Deflate, MethodZstd} {
+		methods = append(methods, methodName(m))
+	}
+
+	fmt.Fprintf(w, "%s %s\n", prog, version)
+	fmt.Fprintf(w, "commit:      %s\n", commit)
+	fmt.Fprintf(w, "go:          %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(w, "build tags:  %s\n", tags)
+	fmt.Fprintf(w, "converter:   %s\n", codepagezip.Backend)
+	fmt.Fprintf(w, "formats:     zip, gzip, bzip2, xz, iso9660\n")
+	fmt.Fprintf(w, "zip methods: %s\n", strings.Join(methods, ", "))
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mixcode/codepage-unzip/codepagezip"
+)
+
+var warningsAsErrors = false // fail the run if there are warnings
+
+// kinds of warnings
+const (
+	WarnName      = codepagezip.WarnName      // a suspicious name
+	WarnLossy     = codepagezip.WarnLossy     // a name that could not be converted
+	WarnEntry     = "entry"                   // an entry extracted differently from what it claims
+	WarnSkipped   = codepagezip.WarnSkipped   // an entry that was not extracted
+	WarnTimestamp = codepagezip.WarnTimestamp // an implausible modification time
+	WarnFs        = "fs"                      // names the output filesystem may not accept
+)
+
+// the warnings of the current archive by kind
+var warnings = make(map[string]int)
+
+// print a warning and count it. Warnings are problems that do not stop the extraction.
+func warnf(kind, format string, a ...any) {
+	warnings[kind]++
+	fmt.Fprintf(os.Stderr, tr("Warning: ")+format+"\n", a...)
+}
+
+// print the number of warnings by kind; returns an error with -warnings-as-errors
+func warningSummary() error {
+	if len(warnings) == 0 {
+		return nil
+	}
+	kinds := make([]string, 0, len(warnings))
+	total := 0
+	for k, n := range warnings {
+		kinds = append(kinds, fmt.Sprintf("%s: %d", k, n))
+		total += n
+	}
+	sort.Strings(kinds)
+	summary := fmt.Sprintf("%d warnings (%s)", total, strings.Join(kinds, ", "))
+	if warningsAsErrors {
+		return fmt.Errorf("%s, and -warnings-as-errors is given", summary)
+	}
+	fmt.Fprintf(os.Stderr, "%s\n", summary)
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+var widthFold = false // fold full-width ASCII and half-width katakana in names
+
+// full-width katakana for U+FF61 to U+FF9F
+var halfwidthKana = []rune("。「」、・ヲァィゥェォャュョッーアイウエオカキクケコサシスセソタチツテトナニヌネノハヒフヘホマミムメモヤユヨラリルレロワン゛゜")
+
+// compose a katakana with a following half-width (semi-)voiced sound mark
+func composeVoiced(base, mark rune) (rune, bool) {
+	switch mark {
+	case 0xff9e: // dakuten
+		switch {
+		case 'カ' <= base && base <= 'チ' && (base-'カ')%2 == 0,
+			base == 'ツ', base == 'テ', base == 'ト':
+			return base + 1, true
+		case 'ハ' <= base && base <= 'ホ' && (base-'ハ')%3 == 0:
+			return base + 1, true
+		case base == 'ウ':
+			return 'ヴ', true
+		case base == 'ワ':
+			return 'ヷ', true
+		case base == 'ヲ':
+			return 'ヺ', true
+		}
+	case 0xff9f: // handakuten
+		if 'ハ' <= base && base <= 'ホ' && (base-'ハ')%3 == 0 {
+			return base + 2, true
+		}
+	}
+	return 0, false
+}
+
+// fold full-width ASCII to ASCII, and half-width katakana to full-width.
+// The full-width solidus and reverse solidus are kept, as they would become path separators.
+func foldWidth(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		r, n := utf8.DecodeRuneInString(s[i:])
+		i += n
+		switch {
+		case r == 0x3000: // ideographic space
+			r = ' '
+		case 0xff01 <= r && r <= 0xff5e && r != '／' && r != '＼':
+			r = r - 0xff01 + '!'
+		case 0xff61 <= r && r <= 0xff9f:
+			r = halfwidthKana[r-0xff61]
+			if next, m := utf8.DecodeRuneInString(s[i:]); m > 0 {
+				if v, ok := composeVoiced(r, next); ok {
+					r = v
+					i += m
+				}
+			}
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
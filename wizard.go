@@ -0,0 +1,146 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mixcode/codepage-unzip/codepagezip"
+)
+
+var wizard = false // ask for the options interactively
+
+// codepages offered by the wizard
+var wizardCodepages = []string{"CP932", "EUC-JP", "CP949", "GBK", "BIG5", "CP866", "CP1251", "CP437", "CP850"}
+
+const wizardSamples = 3 // names to show for each codepage
+
+// read a line from stdin with a default value
+func askLine(r *bufio.Reader, prompt, def string) string {
+	if def != "" {
+		fmt.Printf("%s [%s]: ", prompt, def)
+	} else {
+		fmt.Printf("%s: ", prompt)
+	}
+	s, _ := r.ReadString('\n')
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// quote an argument for a POSIX shell if needed
+func shellQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"\\$`!*?[](){}<>|&;#~") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// preview the names of an archive converted from a codepage; returns the number of names that cannot be converted
+func previewCodepage(files []*zip.File, cp string) (failed int, samples []string) {
+	for _, f := range files {
+		if !f.NonUTF8 {
+			continue
+		}
+		name, err := codepagezip.ConvertString(f.Name, cp, convertTo)
+		if err != nil {
+			failed++
+		} else if len(samples) < wizardSamples {
+			samples = append(samples, name)
+		}
+	}
+	return
+}
+
+// walk the user through choosing an archive, a codepage and a destination, then extract
+func runWizard(args []string) (err error) {
+	in := bufio.NewReader(os.Stdin)
+
+	archive := ""
+	if len(args) > 0 {
+		archive = args[0]
+	}
+	for {
+		archive = askLine(in, tr("Archive file"), archive)
+		if archive == "" {
+			return errors.New(tr("no archive is given"))
+		}
+		if _, e := os.Stat(archive); e == nil {
+			break
+		} else {
+			fmt.Printf("%v\n", e)
+			archive = ""
+		}
+	}
+
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return
+	}
+	legacy := 0
+	for _, f := range zr.File {
+		if f.NonUTF8 {
+			legacy++
+		}
+	}
+	codepage := ""
+	if legacy == 0 {
+		fmt.Print(tr("All names are in UTF-8; no codepage is needed.\n"))
+	} else {
+		fmt.Printf(tr("%d of %d names are not in UTF-8. Names under each codepage:\n"), legacy, len(zr.File))
+		for i, cp := range wizardCodepages {
+			failed, samples := previewCodepage(zr.File, cp)
+			status := tr("all names convert")
+			if failed > 0 {
+				status = fmt.Sprintf(tr("%d names cannot be converted"), failed)
+			}
+			fmt.Printf("%2d) %-7s %s\n", i+1, cp, status)
+			for _, s := range samples {
+				fmt.Printf("      %s\n", s)
+			}
+		}
+		for codepage == "" {
+			s := askLine(in, tr("Codepage number, or a codepage name"), "1")
+			if n, e := strconv.Atoi(s); e == nil {
+				if n >= 1 && n <= len(wizardCodepages) {
+					codepage = wizardCodepages[n-1]
+				}
+			} else {
+				codepage = s
+			}
+		}
+	}
+	zr.Close()
+
+	base := filepath.Base(archive)
+	dest := askLine(in, tr("Destination directory"), strings.TrimSuffix(base, filepath.Ext(base)))
+
+	cmdline := []string{filepath.Base(os.Args[0])}
+	if codepage != "" {
+		cmdline = append(cmdline, "-f", shellQuote(codepage))
+	}
+	cmdline = append(cmdline, "-d", shellQuote(dest), shellQuote(archive))
+	fmt.Printf(tr("\nThe command for next time:\n  %s\n\n"), strings.Join(cmdline, " "))
+	if !promptYN(tr("Extract now? (Y/n)"), true) {
+		return nil
+	}
+
+	if codepage != "" {
+		convertFrom = codepage
+	}
+	destDir = dest
+	err = os.MkdirAll(destDir, fs.ModePerm)
+	if err != nil {
+		return
+	}
+	cmd = CmdUnzip
+	return run([]string{archive})
+}
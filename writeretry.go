@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+var writeRetries = 3 // times to retry creating a file after a transient error
+
+const writeRetryDelay = 100 * time.Millisecond // the first delay between retries; doubled each time
+
+func errnoIn(err error, errnos []syscall.Errno) bool {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+	for _, e := range errnos {
+		if errno == e {
+			return true
+		}
+	}
+	return false
+}
+
+// create an output file, retrying transient errors with backoff.
+// If the system rejects the name, the file is created under an ASCII alternate name in the same directory,
+// which is returned as path.
+func createFileRetry(outpath, name string) (fo *os.File, path string, err error) {
+	path = outpath
+	for i := 0; ; i++ {
+		fo, err = createFile(path)
+		if err == nil {
+			return
+		}
+		if errnoIn(err, transientErrnos) && i < writeRetries {
+			time.Sleep(writeRetryDelay << i)
+			continue
+		}
+		if errnoIn(err, rejectedNameErrnos) && path == outpath {
+			path = alternatePath(outpath)
+			warnf(WarnName, "%s: %v; writing it as %s", name, err, path)
+			i = -1
+			continue
+		}
+		return
+	}
+}
+
+// an ASCII name for a file whose name the system rejects, that does not clobber an existing file
+func alternatePath(outpath string) string {
+	dir, base := filepath.Split(outpath)
+	alt := shortenComponent(slugComponent(base))
+	p := filepath.Join(dir, alt)
+	ext := filepath.Ext(alt)
+	for n := 1; ; n++ {
+		if _, err := os.Lstat(p); os.IsNotExist(err) {
+			return p
+		}
+		p = filepath.Join(dir, alt[:len(alt)-len(ext)]+"_"+strconv.Itoa(n)+ext)
+	}
+}
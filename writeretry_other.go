@@ -0,0 +1,10 @@
+//go:build !windows
+
+package main
+
+import "syscall"
+
+var (
+	transientErrnos    = []syscall.Errno{syscall.EBUSY, syscall.ETXTBSY, syscall.EAGAIN, syscall.EINTR}
+	rejectedNameErrnos = []syscall.Errno{syscall.ENAMETOOLONG, syscall.EILSEQ, syscall.EINVAL}
+)
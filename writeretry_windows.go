@@ -0,0 +1,15 @@
+package main
+
+import "syscall"
+
+const (
+	errorSharingViolation   = syscall.Errno(32)  // ERROR_SHARING_VIOLATION, e.g. while a virus scanner has the file open
+	errorLockViolation      = syscall.Errno(33)  // ERROR_LOCK_VIOLATION
+	errorInvalidName        = syscall.Errno(123) // ERROR_INVALID_NAME
+	errorFilenameExcedRange = syscall.Errno(206) // ERROR_FILENAME_EXCED_RANGE
+)
+
+var (
+	transientErrnos    = []syscall.Errno{errorSharingViolation, errorLockViolation, syscall.ERROR_ACCESS_DENIED}
+	rejectedNameErrnos = []syscall.Errno{errorInvalidName, errorFilenameExcedRange}
+)